@@ -0,0 +1,299 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux || darwin || windows
+
+package dockerkeystore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	dockerclient "github.com/moby/moby/client"
+
+	"github.com/elastic/elastic-agent-autodiscover/bus"
+	"github.com/elastic/elastic-agent-autodiscover/docker"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/keystore"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const (
+	shortIDLen = 12
+
+	// secretsDir is the directory where Swarm mounts the secrets in containers
+	secretsDir = "run/secrets"
+)
+
+// Inspector inspects containers, it is implemented by docker.Client
+type Inspector interface {
+	ContainerInspect(ctx context.Context, container string, options dockerclient.ContainerInspectOptions) (dockerclient.ContainerInspectResult, error)
+}
+
+// Config configures the keystores of a DockerKeystoresRegistry
+type Config struct {
+	// Secrets enables reading the Swarm secrets mounted in containers under /run/secrets. They are read through
+	// the root filesystem of the process of each container, <host_proc_path>/<pid>/root/run/secrets/<secret>,
+	// so it requires access to the processes of the host. Secrets are disabled by default.
+	Secrets bool `config:"secrets"`
+	// HostProcPath is the path where the proc filesystem of the host is mounted
+	HostProcPath string `config:"host_proc_path"`
+	// Env are glob patterns of the names of the environment variables of containers that can be
+	// retrieved, environment variables are disabled when empty
+	Env []string `config:"env"`
+	// RequestTimeout is the timeout of the requests to inspect containers
+	RequestTimeout time.Duration `config:"request_timeout"`
+}
+
+// DefaultConfig returns the configuration used by NewDockerKeystoresRegistry
+func DefaultConfig() Config {
+	return Config{
+		HostProcPath:   "/proc",
+		RequestTimeout: 10 * time.Second,
+	}
+}
+
+// Validate checks the patterns of the environment variables
+func (c *Config) Validate() error {
+	for _, pattern := range c.Env {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid env pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// DockerKeystoresRegistry implements a Provider for Keystore that returns keystores for the container of events
+type DockerKeystoresRegistry struct {
+	logger    *logp.Logger
+	inspector Inspector
+	config    Config
+}
+
+// DockerKeystore allows to retrieve passwords from secrets and environment variables for a given container
+type DockerKeystore struct {
+	container *docker.Container
+	inspector Inspector
+	config    Config
+	logger    *logp.Logger
+}
+
+// NewDockerKeystoresRegistry initializes a DockerKeystoresRegistry, inspector is used to retrieve the
+// process and the environment variables of containers and can be nil when they are not needed.
+func NewDockerKeystoresRegistry(logger *logp.Logger, inspector Inspector, config Config) bus.KeystoreProvider {
+	if logger == nil {
+		logger = logp.NewNopLogger()
+	}
+	return &DockerKeystoresRegistry{
+		logger:    logger,
+		inspector: inspector,
+		config:    config,
+	}
+}
+
+// GetKeystore returns a DockerKeystore for the container of the event, that is held by its container key
+func (dr *DockerKeystoresRegistry) GetKeystore(event bus.Event) keystore.Keystore {
+	container := containerFromEvent(event)
+	if container == nil {
+		dr.logger.Debugf("Cannot retrieve docker container from event: %s", event)
+		return nil
+	}
+	return &DockerKeystore{
+		container: container,
+		inspector: dr.inspector,
+		config:    dr.config,
+		logger:    dr.logger,
+	}
+}
+
+// containerFromEvent returns the container of an event, it is a *docker.Container in events of the watcher,
+// or a map with its id and name in events with docker metadata
+func containerFromEvent(event bus.Event) *docker.Container {
+	switch container := event["container"].(type) {
+	case *docker.Container:
+		return container
+	case mapstr.M:
+		id, _ := container["id"].(string)
+		name, _ := container["name"].(string)
+		if id != "" || name != "" {
+			return &docker.Container{ID: id, Name: name}
+		}
+	}
+	return nil
+}
+
+// Retrieve returns a SecureString with the value referenced by key, that has the docker.<container>.<key> format.
+// The container is the name or ID of the container of the keystore, and the value is read from the secret named key
+// of the container or, if there is no such secret, from the allowed environment variable named key of the container.
+// Dots in the components of a key are escaped as %2E, and percent signs as %25.
+func (k *DockerKeystore) Retrieve(key string) (*keystore.SecureString, error) {
+	// key = "docker.somecontainer.value"
+	if !strings.HasPrefix(key, "docker.") {
+		return nil, keystore.ErrKeyDoesntExists
+	}
+	containerRef, name, err := parseKey(key)
+	if err != nil {
+		k.logger.Debugf(
+			"not valid secret key: %v (%v). Secrets should be of the following format %v",
+			key,
+			err,
+			"docker.somecontainer.value",
+		)
+		return nil, keystore.ErrKeyDoesntExists
+	}
+	if !k.matchContainer(containerRef) {
+		k.logger.Debugf("cannot access secrets of a different container (%v) than: %v", containerRef, k.container.Name)
+		return nil, keystore.ErrKeyDoesntExists
+	}
+
+	// The container is inspected at most once per key, for both its process and its environment
+	inspect := sync.OnceValues(k.inspect)
+
+	if value, err := k.readSecret(inspect, name); err == nil {
+		return keystore.NewSecureString(value), nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		k.logger.Errorf("Could not read secret %v: %v", name, err)
+	}
+
+	if value, ok, err := k.lookupEnv(inspect, name); err != nil {
+		k.logger.Errorf("Could not retrieve environment of container %v: %v", k.container.ID, err)
+	} else if ok {
+		return keystore.NewSecureString([]byte(value)), nil
+	}
+	return nil, keystore.ErrKeyDoesntExists
+}
+
+// parseKey returns the container and name referenced by a docker.<container>.<key> key
+func parseKey(key string) (string, string, error) {
+	tokens := strings.Split(key, ".")
+	if len(tokens) != 3 {
+		return "", "", errors.New("unexpected number of components")
+	}
+	for i, token := range tokens {
+		unescaped, err := url.PathUnescape(token)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid escaping in %q: %w", token, err)
+		}
+		if unescaped == "" {
+			return "", "", errors.New("empty component")
+		}
+		tokens[i] = unescaped
+	}
+	return tokens[1], tokens[2], nil
+}
+
+// matchContainer returns true if ref is the name, the ID or the short ID of the container of the keystore
+func (k *DockerKeystore) matchContainer(ref string) bool {
+	if ref == k.container.Name || ref == k.container.ID {
+		return true
+	}
+	return len(ref) >= shortIDLen && strings.HasPrefix(k.container.ID, ref)
+}
+
+// readSecret reads a Swarm secret from the root filesystem of the process of the container, so containers can
+// only read their own secrets. The secret is read within that root, so symlinks in the container can't point to
+// files outside of it.
+func (k *DockerKeystore) readSecret(inspect func() (container.InspectResponse, error), name string) ([]byte, error) {
+	if !k.config.Secrets || k.inspector == nil || k.container.ID == "" {
+		return nil, os.ErrNotExist
+	}
+	if strings.ContainsRune(name, filepath.Separator) || !filepath.IsLocal(name) {
+		return nil, fmt.Errorf("invalid secret name %q", name)
+	}
+
+	info, err := inspect()
+	if err != nil {
+		return nil, err
+	}
+	if info.State == nil || info.State.Pid == 0 {
+		// The container is not running
+		return nil, os.ErrNotExist
+	}
+	root, err := os.OpenRoot(filepath.Join(k.config.HostProcPath, strconv.Itoa(info.State.Pid), "root"))
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	return root.ReadFile(path.Join(secretsDir, name))
+}
+
+// lookupEnv returns the value of an environment variable of the container if it is allowed
+func (k *DockerKeystore) lookupEnv(inspect func() (container.InspectResponse, error), name string) (string, bool, error) {
+	if k.inspector == nil || k.container.ID == "" || !matchAny(k.config.Env, name) {
+		return "", false, nil
+	}
+
+	info, err := inspect()
+	if err != nil {
+		return "", false, err
+	}
+	if info.Config == nil {
+		return "", false, nil
+	}
+	for _, env := range info.Config.Env {
+		if envName, value, found := strings.Cut(env, "="); found && envName == name {
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// inspect inspects the container of the keystore
+func (k *DockerKeystore) inspect() (container.InspectResponse, error) {
+	ctx := context.Background()
+	if k.config.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, k.config.RequestTimeout)
+		defer cancel()
+	}
+	result, err := k.inspector.ContainerInspect(ctx, k.container.ID, dockerclient.ContainerInspectOptions{})
+	if err != nil {
+		return container.InspectResponse{}, err
+	}
+	return result.Container, nil
+}
+
+// GetConfig returns config.C representation of the key / secret pair to be merged with other
+// loaded configuration.
+func (k *DockerKeystore) GetConfig() (*config.C, error) {
+	return nil, nil
+}
+
+// IsPersisted return if the keystore is physically persisted on disk.
+func (k *DockerKeystore) IsPersisted() bool {
+	return true
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux || darwin || windows
+
+package dockerkeystore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-autodiscover/bus"
+	"github.com/elastic/elastic-agent-autodiscover/docker"
+	"github.com/elastic/elastic-agent-libs/logp/logptest"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const (
+	containerID      = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	otherContainerID = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	containerPid     = 1234
+	otherPid         = 5678
+)
+
+type mockInspector struct {
+	env   map[string][]string
+	pids  map[string]int
+	calls int
+}
+
+func (m *mockInspector) ContainerInspect(_ context.Context, id string, _ dockerclient.ContainerInspectOptions) (dockerclient.ContainerInspectResult, error) {
+	m.calls++
+	return dockerclient.ContainerInspectResult{
+		Container: container.InspectResponse{
+			ID:     id,
+			State:  &container.State{Pid: m.pids[id]},
+			Config: &container.Config{Env: m.env[id]},
+		},
+	}, nil
+}
+
+// newTestRegistry creates a registry reading secrets from a proc filesystem with the processes of two containers,
+// with their secrets mounted by Swarm in <proc>/<pid>/root/run/secrets
+func newTestRegistry(t *testing.T, inspector *mockInspector, env ...string) bus.KeystoreProvider {
+	proc := t.TempDir()
+	inspector.pids = map[string]int{containerID: containerPid, otherContainerID: otherPid}
+	secrets := filepath.Join(proc, strconv.Itoa(containerPid), "root", "run", "secrets")
+	require.NoError(t, os.MkdirAll(secrets, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "db_password"), []byte("from_secret"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "tls.key"), []byte("tls_key"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "..", "agent_password"), []byte("agent_secret"), 0o600))
+
+	otherSecrets := filepath.Join(proc, strconv.Itoa(otherPid), "root", "run", "secrets")
+	require.NoError(t, os.MkdirAll(otherSecrets, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(otherSecrets, "es_password"), []byte("other_secret"), 0o600))
+	// Symlinks are resolved in the root filesystem of the container, they can't point to the host
+	require.NoError(t, os.Symlink(filepath.Join(otherSecrets, "es_password"), filepath.Join(secrets, "absolute_link")))
+	require.NoError(t, os.Symlink(filepath.Join("..", "..", "..", strconv.Itoa(otherPid), "root", "run", "secrets", "es_password"), filepath.Join(secrets, "relative_link")))
+
+	config := DefaultConfig()
+	config.Secrets = true
+	config.HostProcPath = proc
+	config.Env = env
+	return NewDockerKeystoresRegistry(logptest.NewTestingLogger(t, ""), inspector, config)
+}
+
+func TestGetKeystore(t *testing.T) {
+	registry := newTestRegistry(t, &mockInspector{})
+
+	assert.Nil(t, registry.GetKeystore(bus.Event{}))
+	assert.Nil(t, registry.GetKeystore(bus.Event{"container": "mycontainer"}))
+	assert.NotNil(t, registry.GetKeystore(bus.Event{"container": &docker.Container{ID: containerID, Name: "mycontainer"}}))
+	assert.NotNil(t, registry.GetKeystore(bus.Event{"container": mapstr.M{"id": containerID, "name": "mycontainer"}}))
+}
+
+func TestRetrieve(t *testing.T) {
+	inspector := &mockInspector{env: map[string][]string{
+		containerID: {"DB_USER=elastic", "DB_PASSWORD=from_env", "API_TOKEN=token", "PATH=/bin"},
+	}}
+	registry := newTestRegistry(t, inspector, "DB_*", "API_TOKEN")
+	k := registry.GetKeystore(bus.Event{"container": &docker.Container{ID: containerID, Name: "my.container"}})
+
+	tests := map[string]struct {
+		key      string
+		expected string
+		err      bool
+	}{
+		"secret by container name": {
+			key:      "docker.my%2Econtainer.db_password",
+			expected: "from_secret",
+		},
+		"secret by container ID": {
+			key:      "docker." + containerID + ".db_password",
+			expected: "from_secret",
+		},
+		"secret by short container ID": {
+			key:      "docker." + containerID[:12] + ".db_password",
+			expected: "from_secret",
+		},
+		"secret with escaped dots": {
+			key:      "docker.my%2Econtainer.tls%2Ekey",
+			expected: "tls_key",
+		},
+		"allowed env var": {
+			key:      "docker.my%2Econtainer.DB_USER",
+			expected: "elastic",
+		},
+		"allowed env var by exact name": {
+			key:      "docker.my%2Econtainer.API_TOKEN",
+			expected: "token",
+		},
+		"not allowed env var": {
+			key: "docker.my%2Econtainer.PATH",
+			err: true,
+		},
+		"missing env var": {
+			key: "docker.my%2Econtainer.DB_HOST",
+			err: true,
+		},
+		"different container": {
+			key: "docker.other.db_password",
+			err: true,
+		},
+		"secret of a different container": {
+			key: "docker.my%2Econtainer.es_password",
+			err: true,
+		},
+		"secret outside of the secrets directory": {
+			key: "docker.my%2Econtainer.agent_password",
+			err: true,
+		},
+		"absolute symlink to a secret of a different container": {
+			key: "docker.my%2Econtainer.absolute_link",
+			err: true,
+		},
+		"relative symlink to a secret of a different container": {
+			key: "docker.my%2Econtainer.relative_link",
+			err: true,
+		},
+		"too short container ID": {
+			key: "docker." + containerID[:4] + ".db_password",
+			err: true,
+		},
+		"path traversal": {
+			key: "docker.my%2Econtainer.%2E%2E%2Fdb_password",
+			err: true,
+		},
+		"wrong format": {
+			key: "docker.my.container.db_password",
+			err: true,
+		},
+		"not docker": {
+			key: "kubernetes.ns.secret.key",
+			err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			secure, err := k.Retrieve(test.key)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			value, err := secure.Get()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(value))
+		})
+	}
+}
+
+func TestRetrieveSecretsDisabledByDefault(t *testing.T) {
+	inspector := &mockInspector{pids: map[string]int{containerID: os.Getpid()}}
+	registry := NewDockerKeystoresRegistry(logptest.NewTestingLogger(t, ""), inspector, DefaultConfig())
+	k := registry.GetKeystore(bus.Event{"container": &docker.Container{ID: containerID, Name: "mycontainer"}})
+	_, err := k.Retrieve("docker.mycontainer.db_password")
+	assert.Error(t, err)
+	assert.Equal(t, 0, inspector.calls)
+}
+
+func TestRetrieveSecretOfStoppedContainer(t *testing.T) {
+	inspector := &mockInspector{}
+	registry := newTestRegistry(t, inspector)
+	delete(inspector.pids, containerID)
+	k := registry.GetKeystore(bus.Event{"container": &docker.Container{ID: containerID, Name: "mycontainer"}})
+	_, err := k.Retrieve("docker.mycontainer.db_password")
+	assert.Error(t, err)
+}
+
+func TestRetrieveSecretsTakePrecedence(t *testing.T) {
+	inspector := &mockInspector{env: map[string][]string{
+		containerID: {"db_password=from_env"},
+	}}
+	registry := newTestRegistry(t, inspector, "*")
+	k := registry.GetKeystore(bus.Event{"container": &docker.Container{ID: containerID, Name: "mycontainer"}})
+
+	secure, err := k.Retrieve("docker.mycontainer.db_password")
+	require.NoError(t, err)
+	value, err := secure.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "from_secret", string(value))
+
+	_, err = k.Retrieve("docker.mycontainer.missing")
+	assert.Error(t, err)
+	assert.Equal(t, 2, inspector.calls, "container should be inspected once per key")
+}
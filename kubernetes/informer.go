@@ -75,6 +75,18 @@ func NewInformer(client kubernetes.Interface, resource Resource, opts WatchOptio
 			},
 		}
 
+		objType = "event"
+	case *EventV1:
+		e := client.EventsV1().Events(opts.Namespace)
+		listwatch = &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return e.List(ctx, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return e.Watch(ctx, options)
+			},
+		}
+
 		objType = "event"
 	case *Node:
 		n := client.CoreV1().Nodes()
@@ -289,7 +301,7 @@ func NewInformer(client kubernetes.Interface, resource Resource, opts WatchOptio
 		objType = "clusterrolebinding"
 
 	case *NetworkPolicy:
-		np := client.ExtensionsV1beta1().NetworkPolicies(opts.Namespace)
+		np := client.NetworkingV1().NetworkPolicies(opts.Namespace)
 		listwatch = &cache.ListWatch{
 			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
 				return np.List(ctx, options)
@@ -301,6 +313,58 @@ func NewInformer(client kubernetes.Interface, resource Resource, opts WatchOptio
 
 		objType = "networkpolicy"
 
+	case *Ingress:
+		ing := client.NetworkingV1().Ingresses(opts.Namespace)
+		listwatch = &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return ing.List(ctx, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return ing.Watch(ctx, options)
+			},
+		}
+
+		objType = "ingress"
+
+	case *EndpointSlice:
+		es := client.DiscoveryV1().EndpointSlices(opts.Namespace)
+		listwatch = &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return es.List(ctx, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return es.Watch(ctx, options)
+			},
+		}
+
+		objType = "endpointslice"
+
+	case *PodDisruptionBudget:
+		pdb := client.PolicyV1().PodDisruptionBudgets(opts.Namespace)
+		listwatch = &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return pdb.List(ctx, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return pdb.Watch(ctx, options)
+			},
+		}
+
+		objType = "poddisruptionbudget"
+
+	case *HorizontalPodAutoscaler:
+		hpa := client.AutoscalingV2().HorizontalPodAutoscalers(opts.Namespace)
+		listwatch = &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return hpa.List(ctx, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return hpa.Watch(ctx, options)
+			},
+		}
+
+		objType = "horizontalpodautoscaler"
+
 	default:
 		return nil, "", fmt.Errorf("unsupported resource type for watching %T", resource)
 	}
@@ -308,7 +372,7 @@ func NewInformer(client kubernetes.Interface, resource Resource, opts WatchOptio
 	if indexers == nil {
 		indexers = cache.Indexers{}
 	}
-	return cache.NewSharedIndexInformer(listwatch, resource, opts.SyncTimeout, indexers), objType, nil
+	return cache.NewSharedIndexInformer(listwatch, resource, opts.resyncPeriod(), indexers), objType, nil
 }
 
 // NewMetadataInformer creates an informer for a given resource that only tracks the resource metadata.
@@ -327,7 +391,7 @@ func NewMetadataInformer(client metadata.Interface, gvr schema.GroupVersionResou
 			},
 		},
 		&metav1.PartialObjectMetadata{},
-		opts.SyncTimeout,
+		opts.resyncPeriod(),
 		indexers,
 	)
 	return informer
@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNewInformer(t *testing.T) {
+	objectMeta := metav1.ObjectMeta{
+		Name:      "test",
+		Namespace: "test",
+	}
+	tests := []struct {
+		name     string
+		resource Resource
+		objType  string
+		create   func(client kubernetes.Interface) error
+	}{
+		{
+			name:     "events.k8s.io/v1 event",
+			resource: &EventV1{},
+			objType:  "event",
+			create: func(client kubernetes.Interface) error {
+				_, err := client.EventsV1().Events("test").Create(context.Background(), &EventV1{ObjectMeta: objectMeta}, metav1.CreateOptions{})
+				return err
+			},
+		},
+		{
+			name:     "endpointslice",
+			resource: &EndpointSlice{},
+			objType:  "endpointslice",
+			create: func(client kubernetes.Interface) error {
+				_, err := client.DiscoveryV1().EndpointSlices("test").Create(context.Background(), &EndpointSlice{ObjectMeta: objectMeta}, metav1.CreateOptions{})
+				return err
+			},
+		},
+		{
+			name:     "ingress",
+			resource: &Ingress{},
+			objType:  "ingress",
+			create: func(client kubernetes.Interface) error {
+				_, err := client.NetworkingV1().Ingresses("test").Create(context.Background(), &Ingress{ObjectMeta: objectMeta}, metav1.CreateOptions{})
+				return err
+			},
+		},
+		{
+			name:     "networkpolicy",
+			resource: &NetworkPolicy{},
+			objType:  "networkpolicy",
+			create: func(client kubernetes.Interface) error {
+				_, err := client.NetworkingV1().NetworkPolicies("test").Create(context.Background(), &NetworkPolicy{ObjectMeta: objectMeta}, metav1.CreateOptions{})
+				return err
+			},
+		},
+		{
+			name:     "poddisruptionbudget",
+			resource: &PodDisruptionBudget{},
+			objType:  "poddisruptionbudget",
+			create: func(client kubernetes.Interface) error {
+				_, err := client.PolicyV1().PodDisruptionBudgets("test").Create(context.Background(), &PodDisruptionBudget{ObjectMeta: objectMeta}, metav1.CreateOptions{})
+				return err
+			},
+		},
+		{
+			name:     "horizontalpodautoscaler",
+			resource: &HorizontalPodAutoscaler{},
+			objType:  "horizontalpodautoscaler",
+			create: func(client kubernetes.Interface) error {
+				_, err := client.AutoscalingV2().HorizontalPodAutoscalers("test").Create(context.Background(), &HorizontalPodAutoscaler{ObjectMeta: objectMeta}, metav1.CreateOptions{})
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			require.NoError(t, test.create(client))
+
+			informer, objType, err := NewInformer(client, test.resource, WatchOptions{}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, test.objType, objType)
+
+			stop := make(chan struct{})
+			defer close(stop)
+			go informer.Run(stop)
+			require.True(t, cache.WaitForCacheSync(stop, informer.HasSynced))
+
+			assert.Eventually(t, func() bool {
+				_, exists, _ := informer.GetStore().GetByKey("test/test")
+				return exists
+			}, 5*time.Second, time.Millisecond)
+		})
+	}
+}
+
+func TestNewInformerUnsupportedResource(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	_, _, err := NewInformer(client, &metav1.Status{}, WatchOptions{}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package k8skeystore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// AccessPolicyConfig controls which secrets, config maps and mounted secrets can be retrieved by the keystore
+// of a namespace. Patterns are glob patterns where * matches any sequence of characters and ? any single character.
+//
+// Lookups are checked in the following order:
+//   - objects matching Deny are never retrieved
+//   - objects of other namespaces are only retrieved from SharedNamespaces
+//   - objects of namespaces listed in Namespaces are only retrieved if their name matches one of the allowed patterns
+type AccessPolicyConfig struct {
+	// Namespaces restricts the names of the objects that can be retrieved in some namespaces
+	Namespaces []NamespaceAccessConfig `config:"namespaces"`
+	// SharedNamespaces are namespaces whose objects can be retrieved from all namespaces
+	SharedNamespaces []string `config:"shared_namespaces"`
+	// Deny are <namespace>/<name> patterns of objects that are never retrieved
+	Deny []string `config:"deny"`
+}
+
+// NamespaceAccessConfig lists the patterns of the names of the objects that can be retrieved in a namespace
+type NamespaceAccessConfig struct {
+	Namespace string   `config:"namespace" validate:"required"`
+	Allow     []string `config:"allow"`
+}
+
+// Validate checks the patterns of the policy
+func (c *AccessPolicyConfig) Validate() error {
+	for _, pattern := range c.Deny {
+		if strings.Count(pattern, "/") != 1 {
+			return fmt.Errorf("deny pattern %q must have the <namespace>/<name> format", pattern)
+		}
+	}
+	patterns := append(append([]string{}, c.SharedNamespaces...), c.Deny...)
+	for _, namespace := range c.Namespaces {
+		patterns = append(patterns, namespace.Allow...)
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// accessPolicy checks and audits the lookups of the keystores
+type accessPolicy struct {
+	config AccessPolicyConfig
+	// err is set if the config is not valid, all lookups are denied then
+	err   error
+	audit *logp.Logger
+}
+
+// newAccessPolicy returns the policy of config. Configs are validated when they are unpacked, but a policy
+// built from an invalid config denies all lookups instead of ignoring the invalid patterns.
+func newAccessPolicy(config AccessPolicyConfig, logger *logp.Logger) *accessPolicy {
+	p := &accessPolicy{
+		config: config,
+		audit:  logger.Named("audit"),
+	}
+	if err := config.Validate(); err != nil {
+		p.err = err
+		logger.Errorf("Invalid keystore access policy, all lookups are denied: %v", err)
+	}
+	return p
+}
+
+// check returns whether the keystore of namespace can retrieve ref. Every decision is logged in the audit log,
+// denied lookups at info level and granted lookups at debug level.
+func (p *accessPolicy) check(namespace string, ref reference) bool {
+	allowed, reason := p.decide(namespace, ref)
+	log, message, outcome := p.audit.Debugw, "Keystore lookup granted", "success"
+	if !allowed {
+		log, message, outcome = p.audit.Infow, "Keystore lookup denied", "failure"
+	}
+	log(message,
+		"event.action", "keystore-lookup",
+		"event.outcome", outcome,
+		"event.reason", reason,
+		"kubernetes.namespace", namespace,
+		"keystore.reference.namespace", ref.namespace,
+		"keystore.reference.kind", string(ref.kind),
+		"keystore.reference.name", ref.name,
+		"keystore.reference.key", ref.key,
+	)
+	return allowed
+}
+
+// decide returns whether the keystore of namespace can retrieve ref, and the reason of the decision
+func (p *accessPolicy) decide(namespace string, ref reference) (bool, string) {
+	if p.err != nil {
+		return false, "invalid access policy"
+	}
+	if matchAny(p.config.Deny, ref.namespace+"/"+ref.name) {
+		return false, "denylisted"
+	}
+	if ref.namespace != namespace && !matchAny(p.config.SharedNamespaces, ref.namespace) {
+		return false, "different namespace"
+	}
+	for _, ns := range p.config.Namespaces {
+		if ns.Namespace != ref.namespace {
+			continue
+		}
+		if !matchAny(ns.Allow, ref.name) {
+			return false, "name not allowed in namespace"
+		}
+		return true, "name allowed in namespace"
+	}
+	if ref.namespace != namespace {
+		return true, "shared namespace"
+	}
+	return true, "same namespace"
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package k8skeystore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/elastic/elastic-agent-autodiscover/bus"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestAccessPolicy_Decide(t *testing.T) {
+	policy := newAccessPolicy(AccessPolicyConfig{
+		Namespaces: []NamespaceAccessConfig{
+			{Namespace: "shared", Allow: []string{"db-*", "cache"}},
+			{Namespace: "restricted", Allow: []string{"public"}},
+		},
+		SharedNamespaces: []string{"shared", "common-*"},
+		Deny:             []string{"shared/db-admin", "*/root-*"},
+	}, logp.NewNopLogger())
+
+	tests := map[string]struct {
+		namespace string
+		ref       reference
+		allowed   bool
+	}{
+		"same namespace": {
+			namespace: "app",
+			ref:       reference{namespace: "app", kind: kindSecret, name: "password"},
+			allowed:   true,
+		},
+		"different namespace": {
+			namespace: "app",
+			ref:       reference{namespace: "other", kind: kindSecret, name: "password"},
+			allowed:   false,
+		},
+		"shared namespace with allowed name": {
+			namespace: "app",
+			ref:       reference{namespace: "shared", kind: kindSecret, name: "db-user"},
+			allowed:   true,
+		},
+		"shared namespace with not allowed name": {
+			namespace: "app",
+			ref:       reference{namespace: "shared", kind: kindConfigMap, name: "other"},
+			allowed:   false,
+		},
+		"shared namespace pattern": {
+			namespace: "app",
+			ref:       reference{namespace: "common-1", kind: kindSecret, name: "anything"},
+			allowed:   true,
+		},
+		"denied in shared namespace": {
+			namespace: "app",
+			ref:       reference{namespace: "shared", kind: kindSecret, name: "db-admin"},
+			allowed:   false,
+		},
+		"denied in same namespace": {
+			namespace: "app",
+			ref:       reference{namespace: "app", kind: kindFile, name: "root-password"},
+			allowed:   false,
+		},
+		"restricted namespace with not allowed name": {
+			namespace: "restricted",
+			ref:       reference{namespace: "restricted", kind: kindSecret, name: "private"},
+			allowed:   false,
+		},
+		"restricted namespace with allowed name": {
+			namespace: "restricted",
+			ref:       reference{namespace: "restricted", kind: kindSecret, name: "public"},
+			allowed:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			allowed, reason := policy.decide(test.namespace, test.ref)
+			assert.Equal(t, test.allowed, allowed, reason)
+			assert.NotEmpty(t, reason)
+		})
+	}
+}
+
+func TestAccessPolicy_InvalidConfig(t *testing.T) {
+	// Invalid patterns don't fail open when the config is not validated when it is unpacked
+	policy := newAccessPolicy(AccessPolicyConfig{
+		Deny: []string{"prod/[db"},
+	}, logp.NewNopLogger())
+
+	allowed, reason := policy.decide("prod", reference{namespace: "prod", kind: kindSecret, name: "db"})
+	assert.False(t, allowed)
+	assert.Equal(t, "invalid access policy", reason)
+
+	allowed, _ = policy.decide("app", reference{namespace: "app", kind: kindSecret, name: "password"})
+	assert.False(t, allowed)
+}
+
+func TestAccessPolicyConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		config mapstr.M
+		err    bool
+	}{
+		"valid": {
+			config: mapstr.M{
+				"namespaces":        []mapstr.M{{"namespace": "shared", "allow": []string{"db-*"}}},
+				"shared_namespaces": []string{"shared"},
+				"deny":              []string{"*/admin"},
+			},
+		},
+		"deny without namespace": {
+			config: mapstr.M{"deny": []string{"admin"}},
+			err:    true,
+		},
+		"invalid pattern": {
+			config: mapstr.M{"shared_namespaces": []string{"[shared"}},
+			err:    true,
+		},
+		"namespace without name": {
+			config: mapstr.M{"namespaces": []mapstr.M{{"allow": []string{"db-*"}}}},
+			err:    true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var policy AccessPolicyConfig
+			err := config.MustNewConfigFrom(test.config).Unpack(&policy)
+			if test.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetKeystoreAndRetrieveWithAccessPolicy(t *testing.T) {
+	secret := newTestSecret("testing_secret", pass)
+	secret.Namespace = "shared"
+	client := k8sfake.NewSimpleClientset(secret)
+
+	logger, buf := logp.NewInMemoryLocal("", logp.ConsoleEncoderConfig())
+	cfg := DefaultConfig()
+	cfg.AccessPolicy = AccessPolicyConfig{SharedNamespaces: []string{"shared"}}
+	registry := NewKubernetesKeystoresRegistryWithConfig(logger, client, cfg)
+	defer registry.Stop()
+
+	k := registry.GetKeystore(bus.Event{"kubernetes": mapstr.M{"namespace": ns}})
+	secure, err := k.Retrieve("kubernetes.shared.testing_secret.secret_value")
+	require.NoError(t, err)
+	value, err := secure.Get()
+	require.NoError(t, err)
+	assert.Equal(t, []byte(pass), value)
+
+	_, err = k.Retrieve("kubernetes.other.testing_secret.secret_value")
+	assert.Error(t, err)
+
+	logs := buf.String()
+	// Granted lookups are only logged at debug level, as they are the common case
+	assert.Regexp(t, `DEBUG\t.*\tKeystore lookup granted`, logs)
+	assert.Contains(t, logs, `"keystore.reference.namespace": "shared"`)
+	assert.Contains(t, logs, "Keystore lookup denied")
+	assert.Contains(t, logs, `"event.reason": "different namespace"`)
+}
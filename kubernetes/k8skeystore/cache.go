@@ -0,0 +1,334 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package k8skeystore
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	k8s "k8s.io/client-go/kubernetes"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// watchRetryPeriod is the time waited before restarting a failed watch of the secrets of a namespace
+const watchRetryPeriod = 5 * time.Second
+
+// objectKind is the kind of the Kubernetes objects values are retrieved from
+type objectKind string
+
+const (
+	kindSecret    objectKind = "secret"
+	kindConfigMap objectKind = "configmap"
+)
+
+// CacheConfig configures the cache of the secrets and config maps retrieved by the keystores of a registry
+type CacheConfig struct {
+	// TTL is the time an object is cached before it is retrieved again from the API.
+	// Objects are not cached if it is 0 and Watch is disabled, and they don't expire if it is 0
+	// and Watch is enabled.
+	TTL time.Duration `config:"ttl"`
+	// Watch invalidates cached objects when they are updated or deleted, by watching the objects
+	// of the namespaces with retrieved objects. Objects are only cached without TTL while the watch
+	// of their namespace is running.
+	Watch bool `config:"watch"`
+	// MaxSecretsPerNamespace is the maximum number of secrets, and of config maps, cached per namespace,
+	// the least recently used objects are evicted first. There is no limit if it is 0.
+	MaxSecretsPerNamespace int `config:"max_secrets_per_namespace"`
+	// RequestTimeout is the timeout of the requests to the API
+	RequestTimeout time.Duration `config:"request_timeout"`
+}
+
+// DefaultCacheConfig returns the cache configuration used by NewKubernetesKeystoresRegistry
+func DefaultCacheConfig() CacheConfig {
+	return CacheConfig{
+		TTL:                    time.Minute,
+		MaxSecretsPerNamespace: 100,
+		RequestTimeout:         5 * time.Second,
+	}
+}
+
+type cachedObject struct {
+	name       string
+	data       map[string][]byte
+	expiration time.Time
+}
+
+// namespaceKey identifies the objects of a kind in a namespace
+type namespaceKey struct {
+	kind      objectKind
+	namespace string
+}
+
+type namespaceCache struct {
+	entries map[string]*list.Element
+	// lru holds the cached objects, most recently used first
+	lru *list.List
+	// generation is increased when objects are invalidated, so objects fetched before are not stored
+	generation uint64
+	// watching is set when the watch is started, and watched once it is running
+	watching bool
+	watched  bool
+}
+
+// objectCache is a cache of the data of secrets and config maps shared by the keystores of a registry
+type objectCache struct {
+	client k8s.Interface
+	config CacheConfig
+	logger *logp.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+	now    func() time.Time
+
+	mutex      sync.Mutex
+	namespaces map[namespaceKey]*namespaceCache
+}
+
+func newObjectCache(client k8s.Interface, config CacheConfig, logger *logp.Logger) *objectCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &objectCache{
+		client:     client,
+		config:     config,
+		logger:     logger,
+		ctx:        ctx,
+		cancel:     cancel,
+		now:        time.Now,
+		namespaces: map[namespaceKey]*namespaceCache{},
+	}
+}
+
+// enabled returns true if objects are cached
+func (c *objectCache) enabled() bool {
+	return c.config.TTL > 0 || c.config.Watch
+}
+
+// get returns the data of an object, from the cache if it is cached and not expired
+func (c *objectCache) get(kind objectKind, namespace, name string) (map[string][]byte, error) {
+	key := namespaceKey{kind: kind, namespace: namespace}
+	data, generation, ok := c.lookup(key, name)
+	if ok {
+		return data, nil
+	}
+
+	ctx := c.ctx
+	if c.config.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.RequestTimeout)
+		defer cancel()
+	}
+	data, err := c.fetch(ctx, kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.enabled() {
+		c.store(key, name, data, generation)
+	}
+	return data, nil
+}
+
+// fetch retrieves the data of an object from the API. The data of config maps includes their binary data.
+func (c *objectCache) fetch(ctx context.Context, kind objectKind, namespace, name string) (map[string][]byte, error) {
+	switch kind {
+	case kindSecret:
+		secret, err := c.client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return secret.Data, nil
+	case kindConfigMap:
+		configMap, err := c.client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		data := make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+		for k, v := range configMap.BinaryData {
+			data[k] = v
+		}
+		for k, v := range configMap.Data {
+			data[k] = []byte(v)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unsupported object kind %q", kind)
+}
+
+// lookup returns the data of an object if it is cached and not expired. Otherwise it returns the generation
+// of the cache of the namespace, to be passed to store once the object is fetched, and starts the watch of
+// the namespace if it is enabled, so updates of the object are not missed.
+func (c *objectCache) lookup(key namespaceKey, name string) (map[string][]byte, uint64, bool) {
+	if !c.enabled() {
+		return nil, 0, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	nsCache := c.namespaceCache(key)
+	if c.config.Watch && !nsCache.watching {
+		nsCache.watching = true
+		go c.watch(key)
+	}
+
+	element, ok := nsCache.entries[name]
+	if !ok {
+		return nil, nsCache.generation, false
+	}
+	entry, _ := element.Value.(*cachedObject)
+	if !entry.expiration.IsZero() && !c.now().Before(entry.expiration) {
+		nsCache.lru.Remove(element)
+		delete(nsCache.entries, name)
+		return nil, nsCache.generation, false
+	}
+	nsCache.lru.MoveToFront(element)
+	return entry.data, nsCache.generation, true
+}
+
+// namespaceCache returns the cache of the objects of a kind in a namespace, creating it if needed.
+// The mutex must be held.
+func (c *objectCache) namespaceCache(key namespaceKey) *namespaceCache {
+	nsCache, ok := c.namespaces[key]
+	if !ok {
+		nsCache = &namespaceCache{
+			entries: map[string]*list.Element{},
+			lru:     list.New(),
+		}
+		c.namespaces[key] = nsCache
+	}
+	return nsCache
+}
+
+// store caches the data of an object fetched when the cache of its namespace had the given generation.
+// It is not cached if objects of the namespace were invalidated since then, as the data could be stale,
+// or if it wouldn't expire and it is not being watched.
+func (c *objectCache) store(key namespaceKey, name string, data map[string][]byte, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	nsCache := c.namespaceCache(key)
+	if nsCache.generation != generation || (c.config.TTL <= 0 && !nsCache.watched) {
+		return
+	}
+
+	entry := &cachedObject{name: name, data: data}
+	if c.config.TTL > 0 {
+		entry.expiration = c.now().Add(c.config.TTL)
+	}
+	if element, ok := nsCache.entries[name]; ok {
+		element.Value = entry
+		nsCache.lru.MoveToFront(element)
+	} else {
+		nsCache.entries[name] = nsCache.lru.PushFront(entry)
+	}
+
+	for c.config.MaxSecretsPerNamespace > 0 && nsCache.lru.Len() > c.config.MaxSecretsPerNamespace {
+		oldest := nsCache.lru.Back()
+		nsCache.lru.Remove(oldest)
+		evicted, _ := oldest.Value.(*cachedObject)
+		delete(nsCache.entries, evicted.name)
+	}
+}
+
+// invalidate removes an object from the cache
+func (c *objectCache) invalidate(key namespaceKey, name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	nsCache, ok := c.namespaces[key]
+	if !ok {
+		return
+	}
+	nsCache.generation++
+	if element, ok := nsCache.entries[name]; ok {
+		nsCache.lru.Remove(element)
+		delete(nsCache.entries, name)
+	}
+}
+
+// purge removes all the objects of a kind in a namespace from the cache, and sets if they are watched
+func (c *objectCache) purge(key namespaceKey, watched bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if nsCache, ok := c.namespaces[key]; ok {
+		nsCache.generation++
+		nsCache.watched = watched
+		nsCache.entries = map[string]*list.Element{}
+		nsCache.lru.Init()
+	}
+}
+
+// watch invalidates the cached objects of a kind in a namespace when they are updated or deleted until
+// the cache is stopped. Objects cached before the watch is running, or while it is restarted, could have
+// missed updates, so they are purged.
+func (c *objectCache) watch(key namespaceKey) {
+	for {
+		var w watch.Interface
+		var err error
+		switch key.kind {
+		case kindSecret:
+			w, err = c.client.CoreV1().Secrets(key.namespace).Watch(c.ctx, metav1.ListOptions{})
+		case kindConfigMap:
+			w, err = c.client.CoreV1().ConfigMaps(key.namespace).Watch(c.ctx, metav1.ListOptions{})
+		}
+		if err != nil {
+			c.logger.Debugf("Could not watch %s objects in namespace %s: %v", key.kind, key.namespace, err)
+		} else if w != nil {
+			c.purge(key, true)
+			c.handleWatchEvents(key, w)
+		}
+		c.purge(key, false)
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(watchRetryPeriod):
+		}
+	}
+}
+
+func (c *objectCache) handleWatchEvents(key namespaceKey, w watch.Interface) {
+	defer w.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			if event.Type != watch.Modified && event.Type != watch.Deleted {
+				continue
+			}
+			if accessor, err := meta.Accessor(event.Object); err == nil {
+				c.invalidate(key, accessor.GetName())
+			}
+		}
+	}
+}
+
+// stop stops the watches of the cache
+func (c *objectCache) stop() {
+	c.cancel()
+}
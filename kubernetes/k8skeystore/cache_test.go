@@ -0,0 +1,200 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package k8skeystore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/elastic/elastic-agent-autodiscover/bus"
+	"github.com/elastic/elastic-agent-libs/logp/logptest"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func newTestSecret(name, value string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Data: map[string][]byte{
+			"secret_value": []byte(value),
+		},
+	}
+}
+
+func countSecretGets(client *k8sfake.Clientset) int {
+	count := 0
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "get" && action.GetResource().Resource == "secrets" {
+			count++
+		}
+	}
+	return count
+}
+
+func retrieve(t *testing.T, registry *KubernetesKeystoresRegistry, key string) string {
+	t.Helper()
+	k := registry.GetKeystore(bus.Event{"kubernetes": mapstr.M{"namespace": ns}})
+	secure, err := k.Retrieve(key)
+	require.NoError(t, err)
+	value, err := secure.Get()
+	require.NoError(t, err)
+	return string(value)
+}
+
+func TestSecretsCache_TTL(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(newTestSecret("testing_secret", pass))
+	registry := NewKubernetesKeystoresRegistryWithConfig(logptest.NewTestingLogger(t, ""), client, Config{Cache: CacheConfig{
+		TTL:            time.Minute,
+		RequestTimeout: time.Second,
+	}})
+	defer registry.Stop()
+
+	now := time.Now()
+	registry.cache.now = func() time.Time { return now }
+
+	assert.Equal(t, pass, retrieve(t, registry, correctKey))
+	assert.Equal(t, pass, retrieve(t, registry, correctKey))
+	assert.Equal(t, 1, countSecretGets(client))
+
+	_, err := client.CoreV1().Secrets(ns).Update(context.Background(), newTestSecret("testing_secret", "rotated"), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, pass, retrieve(t, registry, correctKey), "secret should be cached until it expires")
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, "rotated", retrieve(t, registry, correctKey))
+	assert.Equal(t, 2, countSecretGets(client))
+}
+
+func TestSecretsCache_Disabled(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(newTestSecret("testing_secret", pass))
+	registry := NewKubernetesKeystoresRegistryWithConfig(logptest.NewTestingLogger(t, ""), client, Config{})
+	defer registry.Stop()
+
+	assert.Equal(t, pass, retrieve(t, registry, correctKey))
+	assert.Equal(t, pass, retrieve(t, registry, correctKey))
+	assert.Equal(t, 2, countSecretGets(client))
+}
+
+func TestSecretsCache_Watch(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(newTestSecret("testing_secret", pass))
+	registry := NewKubernetesKeystoresRegistryWithConfig(logptest.NewTestingLogger(t, ""), client, Config{Cache: CacheConfig{
+		Watch:          true,
+		RequestTimeout: time.Second,
+	}})
+	defer registry.Stop()
+
+	// The secret is not cached until the watch is running, as it wouldn't expire
+	assert.Equal(t, pass, retrieve(t, registry, correctKey))
+	waitWatched(t, registry, kindSecret)
+	assert.Equal(t, 1, countSecretGets(client))
+	assert.Equal(t, pass, retrieve(t, registry, correctKey))
+	assert.Equal(t, pass, retrieve(t, registry, correctKey))
+	assert.Equal(t, 2, countSecretGets(client))
+
+	_, err := client.CoreV1().Secrets(ns).Update(context.Background(), newTestSecret("testing_secret", "rotated"), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return retrieve(t, registry, correctKey) == "rotated"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	err = client.CoreV1().Secrets(ns).Delete(context.Background(), "testing_secret", metav1.DeleteOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		k := registry.GetKeystore(bus.Event{"kubernetes": mapstr.M{"namespace": ns}})
+		_, err := k.Retrieve(correctKey)
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func waitWatched(t *testing.T, registry *KubernetesKeystoresRegistry, kind objectKind) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		registry.cache.mutex.Lock()
+		defer registry.cache.mutex.Unlock()
+		nsCache, ok := registry.cache.namespaces[namespaceKey{kind: kind, namespace: ns}]
+		return ok && nsCache.watched
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSecretsCache_StaleFetch(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(newTestSecret("testing_secret", pass))
+	cache := newObjectCache(client, CacheConfig{TTL: time.Minute}, logptest.NewTestingLogger(t, ""))
+	defer cache.stop()
+
+	key := namespaceKey{kind: kindSecret, namespace: ns}
+	_, generation, ok := cache.lookup(key, "testing_secret")
+	require.False(t, ok)
+
+	// The secret is modified while it is fetched, so the fetched data is not cached
+	cache.invalidate(key, "testing_secret")
+	cache.store(key, "testing_secret", map[string][]byte{"secret_value": []byte(pass)}, generation)
+	_, _, ok = cache.lookup(key, "testing_secret")
+	assert.False(t, ok)
+}
+
+func TestSecretsCache_MaxSecretsPerNamespace(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(
+		newTestSecret("secret_a", "a"),
+		newTestSecret("secret_b", "b"),
+		newTestSecret("secret_c", "c"),
+	)
+	registry := NewKubernetesKeystoresRegistryWithConfig(logptest.NewTestingLogger(t, ""), client, Config{Cache: CacheConfig{
+		TTL:                    time.Minute,
+		MaxSecretsPerNamespace: 2,
+	}})
+	defer registry.Stop()
+
+	keyA := "kubernetes.test_namespace.secret_a.secret_value"
+	keyB := "kubernetes.test_namespace.secret_b.secret_value"
+	keyC := "kubernetes.test_namespace.secret_c.secret_value"
+
+	assert.Equal(t, "a", retrieve(t, registry, keyA))
+	assert.Equal(t, "b", retrieve(t, registry, keyB))
+	// a is used again, so b is the least recently used secret when c is cached
+	assert.Equal(t, "a", retrieve(t, registry, keyA))
+	assert.Equal(t, "c", retrieve(t, registry, keyC))
+	assert.Equal(t, 3, countSecretGets(client))
+
+	assert.Equal(t, "a", retrieve(t, registry, keyA))
+	assert.Equal(t, "c", retrieve(t, registry, keyC))
+	assert.Equal(t, 3, countSecretGets(client))
+
+	assert.Equal(t, "b", retrieve(t, registry, keyB))
+	assert.Equal(t, 4, countSecretGets(client))
+}
+
+func TestGetKeystore_SharedCache(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(newTestSecret("testing_secret", pass))
+	registry := NewKubernetesKeystoresRegistryWithConfig(nil, client, DefaultConfig())
+	defer registry.Stop()
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, pass, retrieve(t, registry, correctKey))
+	}
+	assert.Equal(t, 1, countSecretGets(client))
+}
@@ -18,10 +18,9 @@
 package k8skeystore
 
 import (
-	"context"
 	"strings"
+	"sync"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8s "k8s.io/client-go/kubernetes"
 
 	"github.com/elastic/elastic-agent-autodiscover/bus"
@@ -35,13 +34,42 @@ import (
 type KubernetesKeystoresRegistry struct {
 	logger *logp.Logger
 	client k8s.Interface
+	config Config
+	cache  *objectCache
+	policy *accessPolicy
+
+	mutex     sync.Mutex
+	keystores map[string]keystore.Keystore
 }
 
-// KubernetesSecretsKeystore allows to retrieve passwords from Kubernetes secrets for a given namespace
+// KubernetesSecretsKeystore allows to retrieve passwords from Kubernetes secrets, config maps and
+// mounted secrets for a given namespace
 type KubernetesSecretsKeystore struct {
-	namespace string
-	client    k8s.Interface
-	logger    *logp.Logger
+	namespace   string
+	client      k8s.Interface
+	logger      *logp.Logger
+	cache       *objectCache
+	secretsPath string
+	policy      *accessPolicy
+}
+
+// Config configures the keystores of a KubernetesKeystoresRegistry
+type Config struct {
+	// Cache configures the cache of secrets and config maps shared by the keystores
+	Cache CacheConfig `config:"cache"`
+	// SecretsPath is the directory where secrets are mounted as files, in <namespace>/<name>/<key> paths.
+	// Mounted secrets are referenced as kubernetes.<namespace>.file.<name>.<key>, they are disabled when empty.
+	SecretsPath string `config:"secrets_path"`
+	// AccessPolicy controls the objects that can be retrieved by the keystore of each namespace,
+	// only the objects of the same namespace can be retrieved by default
+	AccessPolicy AccessPolicyConfig `config:"access_policy"`
+}
+
+// DefaultConfig returns the configuration used by NewKubernetesKeystoresRegistry
+func DefaultConfig() Config {
+	return Config{
+		Cache: DefaultCacheConfig(),
+	}
 }
 
 // Factoryk8s Create the right keystore with the configured options
@@ -50,14 +78,32 @@ func Factoryk8s(keystoreNamespace string, ks8client k8s.Interface, logger *logp.
 	return keystore, err
 }
 
-// NewKubernetesKeystoresRegistry initializes a KubernetesKeystoresRegistry
+// NewKubernetesKeystoresRegistry initializes a KubernetesKeystoresRegistry with the default configuration
 func NewKubernetesKeystoresRegistry(logger *logp.Logger, client k8s.Interface) bus.KeystoreProvider {
+	return NewKubernetesKeystoresRegistryWithConfig(logger, client, DefaultConfig())
+}
+
+// NewKubernetesKeystoresRegistryWithConfig initializes a KubernetesKeystoresRegistry with the given configuration.
+// Stop must be called to release the registry when the cache watches objects.
+func NewKubernetesKeystoresRegistryWithConfig(logger *logp.Logger, client k8s.Interface, config Config) *KubernetesKeystoresRegistry {
+	if logger == nil {
+		logger = logp.NewNopLogger()
+	}
 	return &KubernetesKeystoresRegistry{
-		logger: logger,
-		client: client,
+		logger:    logger,
+		client:    client,
+		config:    config,
+		cache:     newObjectCache(client, config.Cache, logger),
+		policy:    newAccessPolicy(config.AccessPolicy, logger),
+		keystores: map[string]keystore.Keystore{},
 	}
 }
 
+// Stop stops the watches of the cache
+func (kr *KubernetesKeystoresRegistry) Stop() {
+	kr.cache.stop()
+}
+
 // GetKeystore return a KubernetesSecretsKeystore if it already exists for a given namespace or creates a new one.
 func (kr *KubernetesKeystoresRegistry) GetKeystore(event bus.Event) keystore.Keystore {
 	namespace := ""
@@ -78,58 +124,86 @@ func (kr *KubernetesKeystoresRegistry) GetKeystore(event bus.Event) keystore.Key
 		}
 	}
 	if namespace != "" {
-		k8sKeystore, _ := Factoryk8s(namespace, kr.client, kr.logger)
+		kr.mutex.Lock()
+		defer kr.mutex.Unlock()
+		if k8sKeystore, ok := kr.keystores[namespace]; ok {
+			return k8sKeystore
+		}
+		k8sKeystore := &KubernetesSecretsKeystore{
+			namespace:   namespace,
+			client:      kr.client,
+			logger:      kr.logger,
+			cache:       kr.cache,
+			secretsPath: kr.config.SecretsPath,
+			policy:      kr.policy,
+		}
+		kr.keystores[namespace] = k8sKeystore
 		return k8sKeystore
 	}
 	kr.logger.Debugf("Cannot retrieve kubernetes namespace from event: %s", event)
 	return nil
 }
 
-// NewKubernetesSecretsKeystore returns an new k8s Keystore
+// NewKubernetesSecretsKeystore returns an new k8s Keystore, secrets are not cached and are retrieved
+// from the API on every call to Retrieve.
 func NewKubernetesSecretsKeystore(keystoreNamespace string, ks8client k8s.Interface, logger *logp.Logger) (keystore.Keystore, error) {
 	keystore := KubernetesSecretsKeystore{
 		namespace: keystoreNamespace,
 		client:    ks8client,
 		logger:    logger,
+		cache: newObjectCache(ks8client, CacheConfig{
+			RequestTimeout: DefaultCacheConfig().RequestTimeout,
+		}, logger),
 	}
 	return &keystore, nil
 }
 
 // Retrieve return a SecureString instance that will contains both the key and the secret.
+// See parseReference for the format of the keys.
 func (k *KubernetesSecretsKeystore) Retrieve(key string) (*keystore.SecureString, error) {
 	// key = "kubernetes.somenamespace.somesecret.value"
-	tokens := strings.Split(key, ".")
-	if len(tokens) > 0 && tokens[0] != "kubernetes" {
+	if !strings.HasPrefix(key, "kubernetes.") {
 		return nil, keystore.ErrKeyDoesntExists
 	}
-	if len(tokens) != 4 {
+	ref, err := parseReference(key)
+	if err != nil {
 		k.logger.Debugf(
-			"not valid secret key: %v. Secrets should be of the following format %v",
+			"not valid secret key: %v (%v). Secrets should be of the following format %v",
 			key,
+			err,
 			"kubernetes.somenamespace.somesecret.value",
 		)
 		return nil, keystore.ErrKeyDoesntExists
 	}
-	ns := tokens[1]
-	secretName := tokens[2]
-	secretVar := tokens[3]
-	if ns != k.namespace {
-		k.logger.Debugf("cannot access Kubernetes secrets from a different namespace (%v) than: %v", ns, k.namespace)
+	if k.policy != nil {
+		if !k.policy.check(k.namespace, ref) {
+			return nil, keystore.ErrKeyDoesntExists
+		}
+	} else if ref.namespace != k.namespace {
+		k.logger.Debugf("cannot access Kubernetes secrets from a different namespace (%v) than: %v", ref.namespace, k.namespace)
 		return nil, keystore.ErrKeyDoesntExists
 	}
-	secretIntefrace := k.client.CoreV1().Secrets(ns)
-	ctx := context.TODO()
-	secret, err := secretIntefrace.Get(ctx, secretName, metav1.GetOptions{})
+
+	if ref.kind == kindFile {
+		value, err := readMountedSecret(k.secretsPath, ref)
+		if err != nil {
+			k.logger.Errorf("Could not read mounted secret %v: %v", ref.name, err)
+			return nil, keystore.ErrKeyDoesntExists
+		}
+		return keystore.NewSecureString(value), nil
+	}
+
+	data, err := k.cache.get(ref.kind, ref.namespace, ref.name)
 	if err != nil {
-		k.logger.Errorf("Could not retrieve secret from k8s API: %v", err)
+		k.logger.Errorf("Could not retrieve %v from k8s API: %v", ref.kind, err)
 		return nil, keystore.ErrKeyDoesntExists
 	}
-	if _, ok := secret.Data[secretVar]; !ok {
-		k.logger.Errorf("Could not retrieve value %v for secret %v", secretVar, secretName)
+	value, ok := data[ref.key]
+	if !ok {
+		k.logger.Errorf("Could not retrieve value %v for %v %v", ref.key, ref.kind, ref.name)
 		return nil, keystore.ErrKeyDoesntExists
 	}
-	secretString := secret.Data[secretVar]
-	return keystore.NewSecureString(secretString), nil
+	return keystore.NewSecureString(value), nil
 }
 
 // GetConfig returns config.C representation of the key / secret pair to be merged with other
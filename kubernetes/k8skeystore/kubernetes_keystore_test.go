@@ -19,9 +19,12 @@ package k8skeystore
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/elastic-agent-autodiscover/bus"
 	"github.com/elastic/elastic-agent-libs/logp/logptest"
@@ -79,6 +82,60 @@ func TestGetKeystoreAndRetrieve(t *testing.T) {
 	assert.Equal(t, bytePassword, secretVal)
 }
 
+func TestGetKeystoreAndRetrieveConfigMap(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testing_config",
+			Namespace: ns,
+		},
+		Data: map[string]string{
+			"endpoint.url": "https://example.com",
+		},
+		BinaryData: map[string][]byte{
+			"ca": []byte("binary"),
+		},
+	})
+
+	kRegistry := NewKubernetesKeystoresRegistry(nil, client)
+	k1 := kRegistry.GetKeystore(bus.Event{"kubernetes": mapstr.M{"namespace": ns}})
+
+	secure, err := k1.Retrieve("kubernetes.test_namespace.configmap.testing_config.endpoint%2Eurl")
+	require.NoError(t, err)
+	value, err := secure.Get()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("https://example.com"), value)
+
+	secure, err = k1.Retrieve("kubernetes.test_namespace.configmap.testing_config.ca")
+	require.NoError(t, err)
+	value, err = secure.Get()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("binary"), value)
+
+	_, err = k1.Retrieve("kubernetes.test_namespace.configmap.testing_config.missing")
+	assert.Error(t, err)
+}
+
+func TestGetKeystoreAndRetrieveMountedSecret(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ns, "testing_secret"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ns, "testing_secret", "secret_value"), []byte(pass), 0o600))
+
+	config := DefaultConfig()
+	config.SecretsPath = dir
+	kRegistry := NewKubernetesKeystoresRegistryWithConfig(nil, k8sfake.NewSimpleClientset(), config)
+	k1 := kRegistry.GetKeystore(bus.Event{"kubernetes": mapstr.M{"namespace": ns}})
+
+	secure, err := k1.Retrieve("kubernetes.test_namespace.file.testing_secret.secret_value")
+	require.NoError(t, err)
+	value, err := secure.Get()
+	require.NoError(t, err)
+	assert.Equal(t, []byte(pass), value)
+
+	k2 := kRegistry.GetKeystore(bus.Event{"kubernetes": mapstr.M{"namespace": "other"}})
+	_, err = k2.Retrieve("kubernetes.test_namespace.file.testing_secret.secret_value")
+	assert.Error(t, err)
+}
+
 func TestGetKeystoreAndRetrieveWithNonAllowedNamespace(t *testing.T) {
 	kRegistry := getKeystoreForWrongValue(t)
 	k1 := kRegistry.GetKeystore(bus.Event{"kubernetes": mapstr.M{"namespace": ns}})
@@ -0,0 +1,90 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package k8skeystore
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// kindFile references secrets mounted as files, they are read from the filesystem instead of the API
+const kindFile objectKind = "file"
+
+// reference is a parsed keystore key, it references the value of key in the object name of a namespace
+type reference struct {
+	namespace string
+	kind      objectKind
+	name      string
+	key       string
+}
+
+// parseReference parses keystore keys, that have the following formats:
+//
+//	kubernetes.<namespace>.<secret>.<key>
+//	kubernetes.<namespace>.secret.<secret>.<key>
+//	kubernetes.<namespace>.configmap.<config map>.<key>
+//	kubernetes.<namespace>.file.<mounted secret>.<key>
+//
+// Dots in the components of a key are escaped as %2E, and percent signs as %25,
+// so the key tls.crt of a secret is referenced as kubernetes.<namespace>.<secret>.tls%2Ecrt.
+func parseReference(key string) (reference, error) {
+	tokens := strings.Split(key, ".")
+	if tokens[0] != "kubernetes" {
+		return reference{}, errors.New("not a kubernetes key")
+	}
+	for i, token := range tokens {
+		unescaped, err := url.PathUnescape(token)
+		if err != nil {
+			return reference{}, fmt.Errorf("invalid escaping in %q: %w", token, err)
+		}
+		if unescaped == "" {
+			return reference{}, errors.New("empty component")
+		}
+		tokens[i] = unescaped
+	}
+
+	switch len(tokens) {
+	case 4:
+		return reference{namespace: tokens[1], kind: kindSecret, name: tokens[2], key: tokens[3]}, nil
+	case 5:
+		ref := reference{namespace: tokens[1], kind: objectKind(tokens[2]), name: tokens[3], key: tokens[4]}
+		switch ref.kind {
+		case kindSecret, kindConfigMap, kindFile:
+			return ref, nil
+		}
+		return reference{}, fmt.Errorf("unknown source %q", tokens[2])
+	}
+	return reference{}, errors.New("unexpected number of components")
+}
+
+// readMountedSecret reads the value of a secret mounted at <secretsPath>/<namespace>/<name>/<key>
+func readMountedSecret(secretsPath string, ref reference) ([]byte, error) {
+	if secretsPath == "" {
+		return nil, errors.New("mounted secrets are not enabled")
+	}
+	for _, component := range []string{ref.namespace, ref.name, ref.key} {
+		if strings.ContainsRune(component, filepath.Separator) || !filepath.IsLocal(component) {
+			return nil, fmt.Errorf("invalid path component %q", component)
+		}
+	}
+	return os.ReadFile(filepath.Join(secretsPath, ref.namespace, ref.name, ref.key))
+}
@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package k8skeystore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := map[string]struct {
+		key      string
+		expected reference
+		err      bool
+	}{
+		"secret": {
+			key:      "kubernetes.ns.mysecret.password",
+			expected: reference{namespace: "ns", kind: kindSecret, name: "mysecret", key: "password"},
+		},
+		"explicit secret": {
+			key:      "kubernetes.ns.secret.mysecret.password",
+			expected: reference{namespace: "ns", kind: kindSecret, name: "mysecret", key: "password"},
+		},
+		"config map": {
+			key:      "kubernetes.ns.configmap.myconfig.endpoint",
+			expected: reference{namespace: "ns", kind: kindConfigMap, name: "myconfig", key: "endpoint"},
+		},
+		"mounted secret": {
+			key:      "kubernetes.ns.file.mysecret.password",
+			expected: reference{namespace: "ns", kind: kindFile, name: "mysecret", key: "password"},
+		},
+		"escaped dots": {
+			key:      "kubernetes.ns.my%2Esecret.tls%2Ecrt",
+			expected: reference{namespace: "ns", kind: kindSecret, name: "my.secret", key: "tls.crt"},
+		},
+		"escaped percent": {
+			key:      "kubernetes.ns.configmap.myconfig.100%25",
+			expected: reference{namespace: "ns", kind: kindConfigMap, name: "myconfig", key: "100%"},
+		},
+		"not kubernetes": {
+			key: "docker.ns.mysecret.password",
+			err: true,
+		},
+		"too short": {
+			key: "kubernetes.ns.mysecret",
+			err: true,
+		},
+		"too long": {
+			key: "kubernetes.ns.secret.mysecret.tls.crt",
+			err: true,
+		},
+		"unknown source": {
+			key: "kubernetes.ns.pod.mypod.name",
+			err: true,
+		},
+		"invalid escaping": {
+			key: "kubernetes.ns.mysecret.pass%2",
+			err: true,
+		},
+		"empty component": {
+			key: "kubernetes.ns..password",
+			err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ref, err := parseReference(test.key)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ref)
+		})
+	}
+}
+
+func TestReadMountedSecret(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "ns", "mysecret"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ns", "mysecret", "tls.key"), []byte("value"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "outside"), []byte("outside"), 0o600))
+
+	value, err := readMountedSecret(dir, reference{namespace: "ns", kind: kindFile, name: "mysecret", key: "tls.key"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	_, err = readMountedSecret("", reference{namespace: "ns", kind: kindFile, name: "mysecret", key: "tls.key"})
+	assert.Error(t, err, "mounted secrets should be disabled without path")
+
+	_, err = readMountedSecret(dir, reference{namespace: "ns", kind: kindFile, name: "mysecret", key: "missing"})
+	assert.Error(t, err)
+
+	for _, ref := range []reference{
+		{namespace: "ns", kind: kindFile, name: "..", key: "outside"},
+		{namespace: "ns", kind: kindFile, name: "mysecret", key: "../../outside"},
+		{namespace: "..", kind: kindFile, name: "..", key: "outside"},
+	} {
+		_, err = readMountedSecret(filepath.Join(dir, "ns"), ref)
+		assert.Error(t, err, "paths outside of the secrets path should not be read")
+	}
+}
@@ -0,0 +1,199 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/elastic/elastic-agent-autodiscover/bus"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// LeaderCallbacks are the callbacks invoked by the LeaderElector on leadership changes
+type LeaderCallbacks = leaderelection.LeaderCallbacks
+
+// LeaderElectionConfig controls the leader election used to run cluster scoped watchers on a single agent
+type LeaderElectionConfig struct {
+	// LeaseName is the name of the Lease object used as lock
+	LeaseName string `config:"leader_lease"`
+	// Namespace where the Lease object lives, if empty the namespace of the agent pod is used
+	Namespace string `config:"leader_namespace"`
+	// Identity of this candidate, if empty the hostname is used
+	Identity string `config:"leader_identity"`
+	// LeaseDuration is the time non-leader candidates wait before trying to acquire the lease
+	LeaseDuration time.Duration `config:"leader_leaseduration"`
+	// RenewDeadline is the time the leader keeps retrying to refresh the lease before giving it up
+	RenewDeadline time.Duration `config:"leader_renewdeadline"`
+	// RetryPeriod is the time candidates wait between actions
+	RetryPeriod time.Duration `config:"leader_retryperiod"`
+}
+
+// InitDefaults initializes the defaults for the config.
+func (c *LeaderElectionConfig) InitDefaults() {
+	c.LeaseDuration = 15 * time.Second
+	c.RenewDeadline = 10 * time.Second
+	c.RetryPeriod = 2 * time.Second
+}
+
+// LeaderElector runs a Lease based leader election and notifies the given callbacks
+// every time this candidate gains or loses the leadership.
+type LeaderElector struct {
+	elector  *leaderelection.LeaderElector
+	identity string
+	ctx      context.Context
+	stop     context.CancelFunc
+	logger   *logp.Logger
+}
+
+// NewLeaderElector initializes a LeaderElector using a Lease lock
+func NewLeaderElector(client kubernetes.Interface, cfg LeaderElectionConfig, callbacks LeaderCallbacks, logger *logp.Logger) (*LeaderElector, error) {
+	if cfg.LeaseName == "" {
+		return nil, errors.New("kubernetes: leader election requires a lease name")
+	}
+
+	identity := cfg.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: unable to get hostname as leader election identity: %w", err)
+		}
+		identity = hostname
+	}
+
+	namespace := cfg.Namespace
+	if namespace == "" {
+		ns, err := InClusterNamespace()
+		if err != nil {
+			ns = "default"
+		}
+		namespace = ns
+	}
+
+	// client-go requires both callbacks to be set
+	if callbacks.OnStartedLeading == nil {
+		callbacks.OnStartedLeading = func(context.Context) {}
+	}
+	if callbacks.OnStoppedLeading == nil {
+		callbacks.OnStoppedLeading = func() {}
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      cfg.LeaseName,
+			Namespace: namespace,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.RenewDeadline,
+		RetryPeriod:     cfg.RetryPeriod,
+		Callbacks:       callbacks,
+		Name:            cfg.LeaseName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: unable to create leader elector: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	return &LeaderElector{
+		elector:  elector,
+		identity: identity,
+		ctx:      ctx,
+		stop:     cancel,
+		logger:   logger.Named("kubernetes"),
+	}, nil
+}
+
+// Start runs the leader election in the background until Stop is called
+func (l *LeaderElector) Start() {
+	go func() {
+		for {
+			// Run returns every time the leadership is lost, keep competing for it
+			// until the elector is stopped.
+			l.elector.Run(l.ctx)
+			select {
+			case <-l.ctx.Done():
+				return
+			default:
+				l.logger.Debugf("leader election lock %s lost by %s, trying to acquire it again", l.elector.GetLeader(), l.identity)
+			}
+		}
+	}()
+}
+
+// Stop stops the leader election and releases the lease if this candidate is the leader
+func (l *LeaderElector) Stop() {
+	l.stop()
+}
+
+// IsLeader returns true if this candidate currently holds the lease
+func (l *LeaderElector) IsLeader() bool {
+	return l.elector.IsLeader()
+}
+
+// GetLeader returns the identity of the last observed leader
+func (l *LeaderElector) GetLeader() string {
+	return l.elector.GetLeader()
+}
+
+// Identity returns the identity of this candidate
+func (l *LeaderElector) Identity() string {
+	return l.identity
+}
+
+// NewBusLeaderCallbacks returns LeaderCallbacks that publish a start event to the given bus when
+// the leadership is acquired and a stop event when it is lost. Events carry the provider and id
+// keys so that cluster scoped configurations are started on exactly one agent.
+func NewBusLeaderCallbacks(b bus.Bus, provider, eventID string, logger *logp.Logger) LeaderCallbacks {
+	return LeaderCallbacks{
+		OnStartedLeading: func(_ context.Context) {
+			logger.Debugf("leader election lock gained, id: %v", eventID)
+			b.Publish(bus.Event{
+				"start":    true,
+				"provider": provider,
+				"id":       eventID,
+				"unique":   "true",
+			})
+		},
+		OnStoppedLeading: func() {
+			logger.Debugf("leader election lock lost, id: %v", eventID)
+			b.Publish(bus.Event{
+				"stop":     true,
+				"provider": provider,
+				"id":       eventID,
+				"unique":   "true",
+			})
+		},
+	}
+}
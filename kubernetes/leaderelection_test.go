@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/elastic/elastic-agent-autodiscover/bus"
+	"github.com/elastic/elastic-agent-libs/logp/logptest"
+)
+
+func testLeaderElectionConfig(identity string) LeaderElectionConfig {
+	cfg := LeaderElectionConfig{
+		LeaseName: "agent-cluster-leader",
+		Namespace: "kube-system",
+		Identity:  identity,
+	}
+	cfg.InitDefaults()
+	cfg.LeaseDuration = 2 * time.Second
+	cfg.RenewDeadline = time.Second
+	cfg.RetryPeriod = 100 * time.Millisecond
+	return cfg
+}
+
+func TestNewLeaderElectorRequiresLease(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	_, err := NewLeaderElector(client, LeaderElectionConfig{}, LeaderCallbacks{}, logptest.NewTestingLogger(t, ""))
+	require.Error(t, err)
+}
+
+func TestLeaderElectorPublishesToBus(t *testing.T) {
+	logger := logptest.NewTestingLogger(t, "")
+	client := fake.NewSimpleClientset()
+	b := bus.New(logger, "test")
+	listener := b.Subscribe("provider")
+	defer listener.Stop()
+
+	callbacks := NewBusLeaderCallbacks(b, "provider-uuid", "cluster-scope", logger)
+	elector, err := NewLeaderElector(client, testLeaderElectionConfig("agent-1"), callbacks, logger)
+	require.NoError(t, err)
+	elector.Start()
+
+	select {
+	case event := <-listener.Events():
+		assert.Equal(t, bus.Event{
+			"start":    true,
+			"provider": "provider-uuid",
+			"id":       "cluster-scope",
+			"unique":   "true",
+		}, event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the start event")
+	}
+	assert.True(t, elector.IsLeader())
+	assert.Equal(t, "agent-1", elector.GetLeader())
+
+	lease, err := client.CoordinationV1().Leases("kube-system").Get(context.Background(), "agent-cluster-leader", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, lease.Spec.HolderIdentity)
+	assert.Equal(t, "agent-1", *lease.Spec.HolderIdentity)
+
+	elector.Stop()
+	select {
+	case event := <-listener.Events():
+		assert.Equal(t, true, event["stop"])
+		assert.Equal(t, "cluster-scope", event["id"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the stop event")
+	}
+}
+
+func TestLeaderElectorSingleLeader(t *testing.T) {
+	logger := logptest.NewTestingLogger(t, "")
+	client := fake.NewSimpleClientset()
+
+	leading := make(chan string, 2)
+	newElector := func(identity string) *LeaderElector {
+		elector, err := NewLeaderElector(client, testLeaderElectionConfig(identity), LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				leading <- identity
+			},
+		}, logger)
+		require.NoError(t, err)
+		return elector
+	}
+
+	first := newElector("agent-1")
+	first.Start()
+	defer first.Stop()
+	select {
+	case id := <-leading:
+		assert.Equal(t, "agent-1", id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the first leader")
+	}
+
+	second := newElector("agent-2")
+	second.Start()
+	defer second.Stop()
+	assert.Never(t, func() bool {
+		return second.IsLeader()
+	}, 500*time.Millisecond, 50*time.Millisecond)
+	assert.Equal(t, "agent-1", second.GetLeader())
+}
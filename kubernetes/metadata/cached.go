@@ -0,0 +1,299 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const (
+	nodeDependency      = "node"
+	namespaceDependency = "namespace"
+)
+
+// dependency is an object the cached metadata of other objects depends on, like the node or namespace of a pod
+type dependency struct {
+	kind string
+	name string
+}
+
+type cachedMeta struct {
+	resourceVersion string
+	meta            mapstr.M
+	dependencies    []dependency
+}
+
+// CachedMetaGen is a MetaGen that memoizes the kubernetes metadata generated by another MetaGen.
+// Metadata is cached by the UID and resourceVersion of the objects, so a new version of an object
+// always generates new metadata. Metadata that depends on other objects, like the node or namespace
+// of a pod, is kept until it is invalidated by the handler returned by DependencyEventHandler.
+//
+// Options are applied to a copy of the cached metadata, after it is generated.
+// Nested slices of the returned metadata are shared between calls and must not be modified.
+type CachedMetaGen struct {
+	metaGen MetaGen
+	store   cache.Store
+
+	mutex   sync.RWMutex
+	entries map[types.UID]cachedMeta
+	// dependents indexes the cached entries by the objects they depend on
+	dependents map[dependency]map[types.UID]struct{}
+	// generation is increased on every invalidation, metadata generated while an invalidation
+	// happened may be stale and is not cached
+	generation uint64
+}
+
+// NewCachedMetaGen creates a CachedMetaGen caching the metadata generated by metaGen.
+// store is the store of the watcher used by metaGen, it is needed to cache GenerateFromName.
+func NewCachedMetaGen(metaGen MetaGen, store cache.Store) *CachedMetaGen {
+	return &CachedMetaGen{
+		metaGen:    metaGen,
+		store:      store,
+		entries:    map[types.UID]cachedMeta{},
+		dependents: map[dependency]map[types.UID]struct{}{},
+	}
+}
+
+// Generate generates metadata from a resource object
+func (c *CachedMetaGen) Generate(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	ecsFields := c.GenerateECS(obj)
+	meta := mapstr.M{
+		"kubernetes": c.GenerateK8s(obj, opts...),
+	}
+	meta.DeepUpdate(ecsFields)
+	return meta
+}
+
+// GenerateECS generates ECS metadata from a resource object, it is not cached
+func (c *CachedMetaGen) GenerateECS(obj kubernetes.Resource) mapstr.M {
+	return c.metaGen.GenerateECS(obj)
+}
+
+// generateECSFromName generates the ECS fields of a node from its name when the cached metagen is a node metagen,
+// it is not cached
+func (c *CachedMetaGen) generateECSFromName(name string) mapstr.M {
+	if nodeGen, ok := c.metaGen.(nodeECSGenerator); ok {
+		return nodeGen.generateECSFromName(name)
+	}
+	return nil
+}
+
+// generateNestedFromName generates the metadata of an object embedded in the metadata of other resources,
+// it is not cached as the cache holds the metadata with the field policy of the cached metagen applied
+func (c *CachedMetaGen) generateNestedFromName(name string, opts ...FieldOptions) mapstr.M {
+	return generateNestedFromName(c.metaGen, name, opts...)
+}
+
+// GenerateK8s generates kubernetes metadata from a resource object
+func (c *CachedMetaGen) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	return c.generate(obj, func() mapstr.M {
+		return c.metaGen.GenerateK8s(obj)
+	}, opts)
+}
+
+// GenerateFromName generates kubernetes metadata from an object name
+func (c *CachedMetaGen) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
+	if c.store == nil {
+		return c.metaGen.GenerateFromName(name, opts...)
+	}
+
+	obj, exists, _ := c.store.GetByKey(name)
+	if !exists {
+		return nil
+	}
+	return c.generate(obj, func() mapstr.M {
+		return c.metaGen.GenerateFromName(name)
+	}, opts)
+}
+
+// Invalidate removes the cached metadata of the given object
+func (c *CachedMetaGen) Invalidate(obj any) {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = d.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.remove(accessor.GetUID())
+	c.generation++
+}
+
+// Purge removes all the cached metadata
+func (c *CachedMetaGen) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = map[types.UID]cachedMeta{}
+	c.dependents = map[dependency]map[types.UID]struct{}{}
+	c.generation++
+}
+
+// invalidateDependents removes the cached metadata of the objects depending on obj: the pods of a node,
+// or the objects in a namespace for namespaces and other namespaced objects, like the workloads of pods.
+// All the cached metadata is removed for other objects.
+func (c *CachedMetaGen) invalidateDependents(obj any) {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = d.Obj
+	}
+
+	var dep dependency
+	switch o := obj.(type) {
+	case *kubernetes.Node:
+		dep = dependency{kind: nodeDependency, name: o.Name}
+	case *kubernetes.Namespace:
+		dep = dependency{kind: namespaceDependency, name: o.Name}
+	default:
+		accessor, err := meta.Accessor(obj)
+		if err != nil || accessor.GetNamespace() == "" {
+			c.Purge()
+			return
+		}
+		dep = dependency{kind: namespaceDependency, name: accessor.GetNamespace()}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for uid := range c.dependents[dep] {
+		c.remove(uid)
+	}
+	c.generation++
+}
+
+// remove removes the cached metadata of an object and its entries in the dependents index,
+// the mutex must be held
+func (c *CachedMetaGen) remove(uid types.UID) {
+	entry, ok := c.entries[uid]
+	if !ok {
+		return
+	}
+	delete(c.entries, uid)
+	for _, dep := range entry.dependencies {
+		delete(c.dependents[dep], uid)
+		if len(c.dependents[dep]) == 0 {
+			delete(c.dependents, dep)
+		}
+	}
+}
+
+// EventHandler returns a handler for the watcher of the cached objects that invalidates the updated
+// and deleted objects before calling next. Watchers only have one handler, so the handler of the watcher
+// has to be passed as next.
+func (c *CachedMetaGen) EventHandler(next kubernetes.ResourceEventHandler) kubernetes.ResourceEventHandler {
+	return kubernetes.ResourceEventHandlerFuncs{
+		AddFunc: next.OnAdd,
+		UpdateFunc: func(obj any) {
+			c.Invalidate(obj)
+			next.OnUpdate(obj)
+		},
+		DeleteFunc: func(obj any) {
+			c.Invalidate(obj)
+			next.OnDelete(obj)
+		},
+	}
+}
+
+// DependencyEventHandler returns a handler for the watchers of objects the cached metadata depends on,
+// like the nodes and namespaces of pods, that invalidates the metadata of the objects depending on the
+// updated and deleted objects before calling next.
+func (c *CachedMetaGen) DependencyEventHandler(next kubernetes.ResourceEventHandler) kubernetes.ResourceEventHandler {
+	return kubernetes.ResourceEventHandlerFuncs{
+		AddFunc: next.OnAdd,
+		UpdateFunc: func(obj any) {
+			c.invalidateDependents(obj)
+			next.OnUpdate(obj)
+		},
+		DeleteFunc: func(obj any) {
+			c.invalidateDependents(obj)
+			next.OnDelete(obj)
+		},
+	}
+}
+
+// generate returns a copy of the cached metadata of obj, calling generate on cache misses
+func (c *CachedMetaGen) generate(obj any, generate func() mapstr.M, opts []FieldOptions) mapstr.M {
+	accessor, err := meta.Accessor(obj)
+	if err != nil || accessor.GetUID() == "" || accessor.GetResourceVersion() == "" {
+		// Objects without identity can't be cached
+		return applyFieldOptions(generate(), opts)
+	}
+	uid, resourceVersion := accessor.GetUID(), accessor.GetResourceVersion()
+
+	c.mutex.RLock()
+	entry, ok := c.entries[uid]
+	generation := c.generation
+	c.mutex.RUnlock()
+	if ok && entry.resourceVersion == resourceVersion {
+		return applyFieldOptions(entry.meta.Clone(), opts)
+	}
+
+	generated := generate()
+	if generated == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	if c.generation == generation {
+		c.remove(uid)
+		deps := dependenciesOf(obj, accessor)
+		c.entries[uid] = cachedMeta{
+			resourceVersion: resourceVersion,
+			meta:            generated,
+			dependencies:    deps,
+		}
+		for _, dep := range deps {
+			if c.dependents[dep] == nil {
+				c.dependents[dep] = map[types.UID]struct{}{}
+			}
+			c.dependents[dep][uid] = struct{}{}
+		}
+	}
+	c.mutex.Unlock()
+	return applyFieldOptions(generated.Clone(), opts)
+}
+
+// dependenciesOf returns the objects the metadata of obj depends on, its namespace and the node of pods
+func dependenciesOf(obj any, accessor metav1.Object) []dependency {
+	var deps []dependency
+	if namespace := accessor.GetNamespace(); namespace != "" {
+		deps = append(deps, dependency{kind: namespaceDependency, name: namespace})
+	}
+	if pod, ok := obj.(*kubernetes.Pod); ok && pod.Spec.NodeName != "" {
+		deps = append(deps, dependency{kind: nodeDependency, name: pod.Spec.NodeName})
+	}
+	return deps
+}
+
+func applyFieldOptions(meta mapstr.M, opts []FieldOptions) mapstr.M {
+	if meta == nil {
+		return nil
+	}
+	for _, option := range opts {
+		option(meta)
+	}
+	return meta
+}
@@ -0,0 +1,289 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// countingMetaGen counts the calls to the wrapped metagen, calling generating when set before generating metadata
+type countingMetaGen struct {
+	MetaGen
+	calls      int
+	generating func()
+}
+
+func (c *countingMetaGen) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	c.calls++
+	if c.generating != nil {
+		c.generating()
+	}
+	return c.MetaGen.GenerateK8s(obj, opts...)
+}
+
+func (c *countingMetaGen) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
+	c.calls++
+	return c.MetaGen.GenerateFromName(name, opts...)
+}
+
+func newCachedTestPod(resourceVersion string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			UID:             types.UID(uid),
+			Namespace:       defaultNs,
+			ResourceVersion: resourceVersion,
+			Labels: map[string]string{
+				"app": "nginx",
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName: "testnode",
+		},
+	}
+}
+
+func TestCachedMetaGen(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	pods := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	pod := newCachedTestPod("1")
+	require.NoError(t, pods.Add(pod))
+
+	podMeta, err := NewPodMetadataGenerator(config.NewConfig(), pods, client, nil, nil, nil, nil, addResourceMetadata)
+	require.NoError(t, err)
+	counting := &countingMetaGen{MetaGen: podMeta}
+	cached := NewCachedMetaGen(counting, pods)
+
+	expected := podMeta.GenerateFromName(defaultNs + "/" + name)
+	assert.Equal(t, expected, cached.GenerateFromName(defaultNs+"/"+name))
+	assert.Equal(t, expected, cached.GenerateFromName(defaultNs+"/"+name))
+	assert.Equal(t, expected, cached.GenerateK8s(pod))
+	assert.Equal(t, 1, counting.calls)
+
+	// returned metadata is a copy that can be modified
+	meta := cached.GenerateFromName(defaultNs + "/" + name)
+	_, _ = meta.Put("pod.name", "modified")
+	assert.Equal(t, expected, cached.GenerateK8s(pod))
+
+	// options are applied to the cached metadata
+	meta = cached.GenerateFromName(defaultNs+"/"+name, WithMetadata("pod"))
+	labels, err := meta.GetValue("pod.labels")
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"app": "nginx"}, labels)
+	assert.Equal(t, 1, counting.calls)
+
+	// new versions of the object generate new metadata
+	pod = newCachedTestPod("2")
+	pod.Labels["app"] = "api"
+	require.NoError(t, pods.Update(pod))
+	meta = cached.GenerateFromName(defaultNs + "/" + name)
+	assert.Equal(t, "api", meta["labels"].(mapstr.M)["app"])
+	assert.Equal(t, 2, counting.calls)
+
+	// objects without resource version are not cached
+	cached.GenerateK8s(newCachedTestPod(""))
+	cached.GenerateK8s(newCachedTestPod(""))
+	assert.Equal(t, 4, counting.calls)
+
+	assert.Nil(t, cached.GenerateFromName("missing"))
+}
+
+func TestCachedMetaGen_EventHandlers(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	pod := newCachedTestPod("1")
+	podMeta, err := NewPodMetadataGenerator(config.NewConfig(), nil, client, nil, nil, nil, nil, addResourceMetadata)
+	require.NoError(t, err)
+	counting := &countingMetaGen{MetaGen: podMeta}
+	cached := NewCachedMetaGen(counting, nil)
+
+	var updates, deletes int
+	next := kubernetes.ResourceEventHandlerFuncs{
+		UpdateFunc: func(any) { updates++ },
+		DeleteFunc: func(any) { deletes++ },
+	}
+
+	cached.GenerateK8s(pod)
+	handler := cached.EventHandler(next)
+	handler.OnUpdate(pod)
+	cached.GenerateK8s(pod)
+	handler.OnDelete(cache.DeletedFinalStateUnknown{Key: defaultNs + "/" + name, Obj: pod})
+	cached.GenerateK8s(pod)
+	assert.Equal(t, 3, counting.calls)
+
+	dependencyHandler := cached.DependencyEventHandler(next)
+	dependencyHandler.OnUpdate(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "testnode"}})
+	cached.GenerateK8s(pod)
+	cached.GenerateK8s(pod)
+	assert.Equal(t, 4, counting.calls)
+
+	assert.Equal(t, 2, updates)
+	assert.Equal(t, 1, deletes)
+}
+
+func TestCachedMetaGen_DependencyEventHandler(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	podMeta, err := NewPodMetadataGenerator(config.NewConfig(), nil, client, nil, nil, nil, nil, addResourceMetadata)
+	require.NoError(t, err)
+	counting := &countingMetaGen{MetaGen: podMeta}
+	cached := NewCachedMetaGen(counting, nil)
+	handler := cached.DependencyEventHandler(kubernetes.ResourceEventHandlerFuncs{})
+
+	pod := newCachedTestPod("1")
+	otherPod := newCachedTestPod("1")
+	otherPod.Name = "other"
+	otherPod.UID = "other-uid"
+	otherPod.Namespace = "other"
+	otherPod.Spec.NodeName = "othernode"
+	generate := func() {
+		cached.GenerateK8s(pod)
+		cached.GenerateK8s(otherPod)
+	}
+
+	generate()
+	assert.Equal(t, 2, counting.calls)
+
+	tests := []struct {
+		name  string
+		obj   any
+		calls int
+	}{
+		{
+			name:  "node of a pod",
+			obj:   &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "testnode"}},
+			calls: 1,
+		},
+		{
+			name:  "node without pods",
+			obj:   &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "emptynode"}},
+			calls: 0,
+		},
+		{
+			name:  "namespace of a pod",
+			obj:   &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+			calls: 1,
+		},
+		{
+			name:  "deleted namespace of a pod",
+			obj:   cache.DeletedFinalStateUnknown{Key: defaultNs, Obj: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: defaultNs}}},
+			calls: 1,
+		},
+		{
+			name:  "namespaced object",
+			obj:   &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: defaultNs}},
+			calls: 1,
+		},
+		{
+			name:  "cluster scoped object",
+			obj:   &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}},
+			calls: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler.OnUpdate(test.obj)
+			calls := counting.calls
+			generate()
+			assert.Equal(t, test.calls, counting.calls-calls)
+		})
+	}
+}
+
+func TestCachedMetaGen_InvalidatedWhileGenerating(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	podMeta, err := NewPodMetadataGenerator(config.NewConfig(), nil, client, nil, nil, nil, nil, addResourceMetadata)
+	require.NoError(t, err)
+	counting := &countingMetaGen{MetaGen: podMeta}
+	cached := NewCachedMetaGen(counting, nil)
+	pod := newCachedTestPod("1")
+
+	// Metadata generated while the cache is invalidated may be stale, so it is not cached and
+	// is generated again by the next call
+	invalidations := []func(){
+		cached.Purge,
+		func() { cached.Invalidate(pod) },
+		func() {
+			cached.DependencyEventHandler(kubernetes.ResourceEventHandlerFuncs{}).OnUpdate(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "testnode"}})
+		},
+	}
+	for _, invalidate := range invalidations {
+		cached.Purge()
+		calls := counting.calls
+		counting.generating = invalidate
+		cached.GenerateK8s(pod)
+		counting.generating = nil
+		cached.GenerateK8s(pod)
+		cached.GenerateK8s(pod)
+		assert.Equal(t, calls+2, counting.calls)
+	}
+}
+
+func newBenchmarkPodMetaGen(b *testing.B) (MetaGen, cache.Store) {
+	client := k8sfake.NewSimpleClientset()
+	cfg, err := config.NewConfigFrom(map[string]any{
+		"include_labels":      []string{"^app", "^team"},
+		"use_regex_include":   true,
+		"include_annotations": []string{"example.com/owner"},
+	})
+	require.NoError(b, err)
+
+	pods := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for i := 0; i < 100; i++ {
+		pod := newCachedTestPod("1")
+		pod.Name = fmt.Sprintf("pod-%d", i)
+		pod.UID = types.UID(fmt.Sprintf("uid-%d", i))
+		pod.Labels["app.kubernetes.io/name"] = "nginx"
+		pod.Labels["team"] = "platform"
+		pod.Annotations = map[string]string{"example.com/owner": "sre"}
+		require.NoError(b, pods.Add(pod))
+	}
+	metaGen, err := NewPodMetadataGenerator(cfg, pods, client, nil, nil, nil, nil, addResourceMetadata)
+	require.NoError(b, err)
+	return metaGen, pods
+}
+
+func BenchmarkPodGenerateFromName(b *testing.B) {
+	metaGen, _ := newBenchmarkPodMetaGen(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		metaGen.GenerateFromName(fmt.Sprintf("%s/pod-%d", defaultNs, i%100))
+	}
+}
+
+func BenchmarkCachedPodGenerateFromName(b *testing.B) {
+	metaGen, pods := newBenchmarkPodMetaGen(b)
+	cached := NewCachedMetaGen(metaGen, pods)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cached.GenerateFromName(fmt.Sprintf("%s/pod-%d", defaultNs, i%100))
+	}
+}
@@ -0,0 +1,415 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Names of the cluster identifier sources provided by this package
+const (
+	ClusterSourceConfig     = "config"
+	ClusterSourceKubeConfig = "kube_config"
+	ClusterSourceKubeadm    = "kubeadm"
+	ClusterSourceOpenShift  = "openshift"
+	ClusterSourceNode       = "node"
+	ClusterSourceKubeSystem = "kube_system"
+)
+
+// DefaultClusterIdentifierSources is the order in which cluster identifier sources are tried by default.
+// The openshift, node and kube_system sources need additional API requests and permissions, so they
+// are only tried when they are added to the sources of the cluster config.
+var DefaultClusterIdentifierSources = []string{
+	ClusterSourceConfig,
+	ClusterSourceKubeConfig,
+	ClusterSourceKubeadm,
+}
+
+// clusterRequestTimeout is the timeout of each request to the API done by the cluster identifier sources
+const clusterRequestTimeout = 5 * time.Second
+
+// DefaultClusterNameNodeLabels are the node labels holding the name of the cluster, set by some
+// cluster provisioners, that are checked by the node source
+var DefaultClusterNameNodeLabels = []string{
+	"alpha.eksctl.io/cluster-name",
+}
+
+// aksClusterNodeLabel is the label of AKS nodes with their node resource group, MC_<resource group>_<cluster>_<location>
+const aksClusterNodeLabel = "kubernetes.azure.com/cluster"
+
+// clusterIdentifiers is a set of the identifiers of a cluster
+type clusterIdentifiers uint8
+
+const (
+	clusterURL clusterIdentifiers = 1 << iota
+	clusterName
+	clusterID
+
+	allClusterIdentifiers = clusterURL | clusterName | clusterID
+)
+
+// registeredClusterSource is a cluster identifier source with the identifiers it can provide, so it is
+// only tried when some of them are missing
+type registeredClusterSource struct {
+	source   ClusterIdentifierSource
+	provides clusterIdentifiers
+}
+
+// ClusterIdentifierConfig configures how the cluster is identified
+type ClusterIdentifierConfig struct {
+	// Name, URL and ID override the identifiers of the cluster when set
+	Name string `config:"name"`
+	URL  string `config:"url"`
+	ID   string `config:"id"`
+	// Sources are the cluster identifier sources in the order they are tried,
+	// DefaultClusterIdentifierSources are used when not set
+	Sources []string `config:"sources"`
+	// NodeLabels are node labels holding the name of the cluster checked by the node source,
+	// in addition to DefaultClusterNameNodeLabels
+	NodeLabels []string `config:"node_labels"`
+}
+
+// ClusterIdentifierSource retrieves the identifiers of the cluster. Identifiers that are not
+// available in the source are left empty.
+type ClusterIdentifierSource func(cfg *Config, client k8sclient.Interface) (ClusterInfo, error)
+
+// clusterInfoKey identifies the cluster info retrieved with a client and a config
+type clusterInfoKey struct {
+	client k8sclient.Interface
+	config string
+}
+
+var (
+	// clusterInfoCache memoizes the cluster info retrieved with each client and config, as the metagens
+	// of all the resources retrieve the same cluster info when they are created
+	clusterInfoCacheMutex sync.Mutex
+	clusterInfoCache      = map[clusterInfoKey]ClusterInfo{}
+
+	clusterSourcesMutex sync.RWMutex
+	clusterSources      = map[string]registeredClusterSource{
+		ClusterSourceConfig: {
+			source: func(cfg *Config, _ k8sclient.Interface) (ClusterInfo, error) {
+				return ClusterInfo{
+					URL:  cfg.Cluster.URL,
+					Name: cfg.Cluster.Name,
+					ID:   cfg.Cluster.ID,
+				}, nil
+			},
+			provides: allClusterIdentifiers,
+		},
+		ClusterSourceKubeConfig: {
+			source: func(cfg *Config, _ k8sclient.Interface) (ClusterInfo, error) {
+				return getClusterInfoFromKubeConfigFile(cfg.KubeConfig)
+			},
+			provides: clusterURL | clusterName,
+		},
+		ClusterSourceKubeadm: {
+			source: func(cfg *Config, client k8sclient.Interface) (ClusterInfo, error) {
+				return getClusterInfoFromKubeadmConfigMap(client, cfg.KubeAdm)
+			},
+			provides: clusterURL | clusterName,
+		},
+		ClusterSourceOpenShift:  {source: getClusterInfoFromOpenShift, provides: clusterURL | clusterName},
+		ClusterSourceNode:       {source: getClusterInfoFromNodes, provides: clusterName},
+		ClusterSourceKubeSystem: {source: getClusterInfoFromKubeSystem, provides: clusterID},
+	}
+)
+
+// RegisterClusterIdentifierSource registers a cluster identifier source that can be added to the
+// sources of the cluster config, it replaces any source registered with the same name.
+// Registered sources are tried while any identifier is missing.
+func RegisterClusterIdentifierSource(name string, source ClusterIdentifierSource) {
+	clusterSourcesMutex.Lock()
+	defer clusterSourcesMutex.Unlock()
+	clusterSources[name] = registeredClusterSource{source: source, provides: allClusterIdentifiers}
+}
+
+func getClusterIdentifierSource(name string) (registeredClusterSource, bool) {
+	clusterSourcesMutex.RLock()
+	defer clusterSourcesMutex.RUnlock()
+	source, ok := clusterSources[name]
+	return source, ok
+}
+
+// validate checks that all the sources are registered
+func (c *ClusterIdentifierConfig) validate() error {
+	for _, name := range c.Sources {
+		if _, ok := getClusterIdentifierSource(name); !ok {
+			return fmt.Errorf("unknown cluster identifier source %q", name)
+		}
+	}
+	return nil
+}
+
+// getClusterInfo returns the cluster info retrieved from the configured sources. The cluster info
+// retrieved with a client is memoized, so sources are only tried once for each client and config.
+func getClusterInfo(cfg *Config, client k8sclient.Interface) (ClusterInfo, error) {
+	// Clients are usually pointers, others can't be used as keys of the cache
+	if client != nil && !reflect.ValueOf(client).Comparable() {
+		return getClusterInfoFromSources(cfg, client)
+	}
+	key := clusterInfoKey{
+		client: client,
+		config: fmt.Sprintf("%+v/%s/%t", cfg.Cluster, cfg.KubeConfig, cfg.KubeAdm),
+	}
+
+	clusterInfoCacheMutex.Lock()
+	clusterInfo, ok := clusterInfoCache[key]
+	clusterInfoCacheMutex.Unlock()
+	if ok {
+		return clusterInfo, nil
+	}
+
+	clusterInfo, err := getClusterInfoFromSources(cfg, client)
+	if err != nil {
+		return ClusterInfo{}, err
+	}
+
+	clusterInfoCacheMutex.Lock()
+	clusterInfoCache[key] = clusterInfo
+	clusterInfoCacheMutex.Unlock()
+	return clusterInfo, nil
+}
+
+// getClusterInfoFromSources tries the configured sources in order. Each identifier is taken from the first
+// source providing it, so sources later in the order are only tried when they can provide some of the
+// missing identifiers. By default, the kubeadm source is not tried when kube_config provides the URL and name.
+func getClusterInfoFromSources(cfg *Config, client k8sclient.Interface) (ClusterInfo, error) {
+	sources := cfg.Cluster.Sources
+	if len(sources) == 0 {
+		sources = DefaultClusterIdentifierSources
+	}
+
+	var clusterInfo ClusterInfo
+	for _, name := range sources {
+		source, ok := getClusterIdentifierSource(name)
+		if !ok {
+			return ClusterInfo{}, fmt.Errorf("unknown cluster identifier source %q", name)
+		}
+		missing := clusterInfo.missing()
+		if missing == 0 {
+			break
+		}
+		if source.provides&missing == 0 {
+			continue
+		}
+		info, err := source.source(cfg, client)
+		if err != nil {
+			continue
+		}
+		clusterInfo.merge(info)
+	}
+
+	if clusterInfo == (ClusterInfo{}) {
+		return ClusterInfo{}, errors.New("unable to retrieve cluster identifiers")
+	}
+	return clusterInfo, nil
+}
+
+// missing returns the identifiers that are still empty
+func (c *ClusterInfo) missing() clusterIdentifiers {
+	var missing clusterIdentifiers
+	if c.URL == "" {
+		missing |= clusterURL
+	}
+	if c.Name == "" {
+		missing |= clusterName
+	}
+	if c.ID == "" {
+		missing |= clusterID
+	}
+	return missing
+}
+
+// merge sets the identifiers that are still empty from other
+func (c *ClusterInfo) merge(other ClusterInfo) {
+	if c.URL == "" {
+		c.URL = other.URL
+	}
+	if c.Name == "" {
+		c.Name = other.Name
+	}
+	if c.ID == "" {
+		c.ID = other.ID
+	}
+}
+
+// getClusterInfoFromKubeSystem uses the UID of the kube-system namespace as ID, it is stable
+// for the whole life of the cluster.
+func getClusterInfoFromKubeSystem(_ *Config, client k8sclient.Interface) (ClusterInfo, error) {
+	if client == nil {
+		return ClusterInfo{}, errors.New("unable to get cluster identifiers from kube-system namespace")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), clusterRequestTimeout)
+	defer cancel()
+	ns, err := client.CoreV1().Namespaces().Get(ctx, "kube-system", metav1.GetOptions{})
+	if err != nil {
+		return ClusterInfo{}, fmt.Errorf("unable to get cluster identifiers from kube-system namespace: %w", err)
+	}
+	return ClusterInfo{ID: string(ns.UID)}, nil
+}
+
+// getClusterInfoFromNodes gets the cluster name from the labels or the providerID of a node.
+// EKS clusters not created with eksctl and k3s clusters not created with k3d don't record their name
+// in their nodes, it can be set with the name of the cluster config or a label added to the nodes
+// with the node labels of the cluster config.
+func getClusterInfoFromNodes(cfg *Config, client k8sclient.Interface) (ClusterInfo, error) {
+	if client == nil {
+		return ClusterInfo{}, errors.New("unable to get cluster identifiers from nodes")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), clusterRequestTimeout)
+	defer cancel()
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return ClusterInfo{}, fmt.Errorf("unable to get cluster identifiers from nodes: %w", err)
+	}
+	if len(nodes.Items) == 0 {
+		return ClusterInfo{}, errors.New("unable to get cluster identifiers from nodes: no nodes found")
+	}
+	node := nodes.Items[0]
+
+	labels := append(append([]string{}, cfg.Cluster.NodeLabels...), DefaultClusterNameNodeLabels...)
+	for _, label := range labels {
+		if name := node.Labels[label]; name != "" {
+			return ClusterInfo{Name: name}, nil
+		}
+	}
+	return ClusterInfo{Name: clusterNameFromProvider(node.Spec.ProviderID, node.Name, node.Labels)}, nil
+}
+
+// clusterNameFromProvider gets the cluster name from the providerID and the labels set by the provider of a node:
+//   - kind: the providerID is kind://<container runtime>/<cluster>/<node>.
+//   - GKE: the name of the node instance is gke-<cluster>-<node pool>-<suffix>, the cluster name is found with
+//     the node pool label.
+//   - AKS: the node resource group is in the kubernetes.azure.com/cluster label, see aksClusterName.
+//   - k3d: the providerID of k3s nodes is k3s://<node>, k3d names them k3d-<cluster>-server-<n> or k3d-<cluster>-agent-<n>.
+func clusterNameFromProvider(providerID, nodeName string, labels map[string]string) string {
+	if group := labels[aksClusterNodeLabel]; group != "" {
+		return aksClusterName(group)
+	}
+
+	scheme, path, found := strings.Cut(providerID, "://")
+	if !found {
+		return ""
+	}
+	parts := strings.Split(path, "/")
+
+	switch scheme {
+	case "kind":
+		// kind://<container runtime>/<cluster>/<node>
+		if len(parts) == 3 {
+			return parts[1]
+		}
+	case "gce":
+		// gce://<project>/<zone>/<instance>
+		pool := labels["cloud.google.com/gke-nodepool"]
+		if len(parts) != 3 || pool == "" {
+			return ""
+		}
+		instance := parts[2]
+		if instance == "" {
+			instance = nodeName
+		}
+		if !strings.HasPrefix(instance, "gke-") {
+			return ""
+		}
+		if i := strings.Index(instance, "-"+pool+"-"); i > len("gke-") {
+			return instance[len("gke-"):i]
+		}
+	case "k3s":
+		// k3s://<node>
+		node, found := strings.CutPrefix(path, "k3d-")
+		if !found {
+			return ""
+		}
+		for _, role := range []string{"-server-", "-agent-"} {
+			if i := strings.LastIndex(node, role); i > 0 {
+				return node[:i]
+			}
+		}
+	}
+	return ""
+}
+
+// aksClusterName gets the cluster name from the node resource group of an AKS cluster, that is
+// MC_<resource group>_<cluster>_<location> unless a custom name was set when the cluster was created.
+// Resource groups and cluster names can contain underscores, names are taken as the last part before the
+// location, so they are only correct for cluster names without underscores.
+func aksClusterName(group string) string {
+	if len(group) < len("MC_") || !strings.EqualFold(group[:len("MC_")], "MC_") {
+		return ""
+	}
+	parts := strings.Split(group[len("MC_"):], "_")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+// getClusterInfoFromOpenShift gets the cluster identifiers from the OpenShift Infrastructure resource
+func getClusterInfoFromOpenShift(_ *Config, client k8sclient.Interface) (ClusterInfo, error) {
+	if client == nil {
+		return ClusterInfo{}, errors.New("unable to get cluster identifiers from OpenShift infrastructure")
+	}
+	restClient, ok := client.Discovery().RESTClient().(*rest.RESTClient)
+	if !ok || restClient == nil {
+		return ClusterInfo{}, errors.New("unable to get cluster identifiers from OpenShift infrastructure: no REST client")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterRequestTimeout)
+	defer cancel()
+	body, err := restClient.Get().AbsPath("/apis/config.openshift.io/v1/infrastructures/cluster").DoRaw(ctx)
+	if err != nil {
+		return ClusterInfo{}, fmt.Errorf("unable to get cluster identifiers from OpenShift infrastructure: %w", err)
+	}
+
+	var infrastructure struct {
+		Status struct {
+			InfrastructureName string `json:"infrastructureName"`
+			APIServerURL       string `json:"apiServerURL"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(body, &infrastructure); err != nil {
+		return ClusterInfo{}, fmt.Errorf("unable to get cluster identifiers from OpenShift infrastructure: %w", err)
+	}
+
+	// The API server of OpenShift clusters is at api.<cluster name>.<base domain>, the infrastructure
+	// name is the cluster name with a random suffix.
+	info := ClusterInfo{
+		URL:  infrastructure.Status.APIServerURL,
+		Name: infrastructure.Status.InfrastructureName,
+	}
+	if u, err := url.Parse(infrastructure.Status.APIServerURL); err == nil {
+		if labels := strings.Split(u.Hostname(), "."); len(labels) > 2 && labels[0] == "api" {
+			info.Name = labels[1]
+		}
+	}
+	return info, nil
+}
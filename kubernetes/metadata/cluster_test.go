@@ -0,0 +1,279 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sclient "k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestGetKubernetesClusterIdentifier(t *testing.T) {
+	t.Setenv("KUBECONFIG", "")
+	kubeSystem := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "kube-system",
+			UID:  types.UID(uid),
+		},
+	}
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "ip-10-0-0-1",
+			Labels: map[string]string{
+				"alpha.eksctl.io/cluster-name": "eks-cluster",
+				"example.com/cluster":          "custom-cluster",
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		settings map[string]any
+		objects  bool
+		expected ClusterInfo
+		err      bool
+	}{
+		{
+			name:    "default sources don't use nodes and kube-system",
+			objects: true,
+			err:     true,
+		},
+		{
+			name: "node and kube-system sources",
+			settings: map[string]any{
+				"cluster.sources": []string{"node", "kube_system"},
+			},
+			objects: true,
+			expected: ClusterInfo{
+				Name: "eks-cluster",
+				ID:   uid,
+			},
+		},
+		{
+			name: "config override",
+			settings: map[string]any{
+				"cluster.name":    "override",
+				"cluster.url":     "https://override:6443",
+				"cluster.sources": []string{"config", "node", "kube_system"},
+			},
+			objects: true,
+			expected: ClusterInfo{
+				Name: "override",
+				URL:  "https://override:6443",
+				ID:   uid,
+			},
+		},
+		{
+			name: "configured order and node labels",
+			settings: map[string]any{
+				"cluster.sources":     []string{"node"},
+				"cluster.node_labels": []string{"example.com/cluster"},
+			},
+			objects: true,
+			expected: ClusterInfo{
+				Name: "custom-cluster",
+			},
+		},
+		{
+			name: "no identifiers",
+			err:  true,
+		},
+		{
+			name: "unknown source",
+			settings: map[string]any{
+				"cluster.sources": []string{"unknown"},
+			},
+			objects: true,
+			err:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := k8sfake.NewSimpleClientset()
+			if test.objects {
+				client = k8sfake.NewSimpleClientset(kubeSystem, node)
+			}
+			cfg, err := config.NewConfigFrom(test.settings)
+			require.NoError(t, err)
+
+			clusterInfo, err := GetKubernetesClusterIdentifier(cfg, client)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, clusterInfo)
+		})
+	}
+}
+
+func TestGetKubernetesClusterIdentifierMemoized(t *testing.T) {
+	t.Setenv("KUBECONFIG", "")
+	client := k8sfake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kubeadm-config",
+			Namespace: "kube-system",
+		},
+		Data: map[string]string{
+			"ClusterConfiguration": "clusterName: kubeadm-cluster\ncontrolPlaneEndpoint: https://kubeadm:6443\n",
+		},
+	})
+
+	// The metagens of all the resources retrieve the cluster info with the same client and config
+	for i := 0; i < 3; i++ {
+		metagen, err := NewResourceMetadataGenerator(config.NewConfig(), client)
+		require.NoError(t, err)
+		assert.Equal(t, ClusterInfo{Name: "kubeadm-cluster", URL: "https://kubeadm:6443"}, metagen.clusterInfo)
+	}
+	assert.Len(t, client.Actions(), 1)
+}
+
+func TestGetKubernetesClusterIdentifierStopsWhenFound(t *testing.T) {
+	kubeConfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(kubeConfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: kubeconfig-cluster
+  cluster:
+    server: https://kubeconfig:6443
+contexts:
+- name: default
+  context:
+    cluster: kubeconfig-cluster
+current-context: default
+`), 0o600))
+	client := k8sfake.NewSimpleClientset()
+	cfg, err := config.NewConfigFrom(map[string]any{
+		"kube_config": kubeConfig,
+	})
+	require.NoError(t, err)
+
+	// kubeadm can't provide the ID, the only identifier missing after kube_config, so it is not tried
+	clusterInfo, err := GetKubernetesClusterIdentifier(cfg, client)
+	require.NoError(t, err)
+	assert.Equal(t, ClusterInfo{Name: "kubeconfig-cluster", URL: "https://kubeconfig:6443"}, clusterInfo)
+	assert.Empty(t, client.Actions())
+
+	// kube_system is still tried for the ID
+	cfg, err = config.NewConfigFrom(map[string]any{
+		"kube_config":     kubeConfig,
+		"cluster.sources": []string{"kube_config", "kubeadm", "kube_system"},
+	})
+	require.NoError(t, err)
+	_, err = GetKubernetesClusterIdentifier(cfg, client)
+	require.NoError(t, err)
+	require.Len(t, client.Actions(), 1)
+	assert.Equal(t, "namespaces", client.Actions()[0].GetResource().Resource)
+}
+
+func TestRegisterClusterIdentifierSource(t *testing.T) {
+	RegisterClusterIdentifierSource("test", func(*Config, k8sclient.Interface) (ClusterInfo, error) {
+		return ClusterInfo{Name: "test-cluster", ID: "test-id"}, nil
+	})
+
+	cfg, err := config.NewConfigFrom(map[string]any{
+		"cluster.sources": []string{"test"},
+	})
+	require.NoError(t, err)
+
+	metagen, err := NewResourceMetadataGenerator(cfg, k8sfake.NewSimpleClientset())
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{
+		"orchestrator": mapstr.M{
+			"cluster": mapstr.M{
+				"name": "test-cluster",
+				"id":   "test-id",
+			},
+		},
+	}, metagen.GenerateECS(nil))
+
+	// unknown sources are rejected when the config is unpacked
+	cfg, err = config.NewConfigFrom(map[string]any{
+		"cluster.sources": []string{"missing"},
+	})
+	require.NoError(t, err)
+	_, err = NewResourceMetadataGenerator(cfg, k8sfake.NewSimpleClientset())
+	assert.Error(t, err)
+}
+
+func TestClusterNameFromProvider(t *testing.T) {
+	gkeLabels := map[string]string{"cloud.google.com/gke-nodepool": "default-pool"}
+	aksProviderID := "azure:///subscriptions/sub/resourceGroups/mc_prod-rg_prod-aks_westeurope/providers/Microsoft.Compute/virtualMachineScaleSets/aks-nodepool1-12345678-vmss/virtualMachines/0"
+	tests := []struct {
+		providerID string
+		labels     map[string]string
+		expected   string
+	}{
+		{providerID: "kind://docker/dev/dev-control-plane", expected: "dev"},
+		{providerID: "gce://project/europe-west1-b/gke-prod-eu-default-pool-1a2b3c4d-x9z8", labels: gkeLabels, expected: "prod-eu"},
+		{providerID: "gce://project/europe-west1-b/gke-prod-eu-default-pool-1a2b3c4d-x9z8", expected: ""},
+		{providerID: aksProviderID, labels: map[string]string{"kubernetes.azure.com/cluster": "MC_prod-rg_prod-aks_westeurope"}, expected: "prod-aks"},
+		{providerID: aksProviderID, labels: map[string]string{"kubernetes.azure.com/cluster": "mc_my_rg_dev_eastus"}, expected: "dev"},
+		{providerID: aksProviderID, labels: map[string]string{"kubernetes.azure.com/cluster": "custom-node-rg"}, expected: ""},
+		{providerID: aksProviderID, expected: ""},
+		{providerID: "k3s://k3d-dev-server-0", expected: "dev"},
+		{providerID: "k3s://k3d-my-cluster-agent-1", expected: "my-cluster"},
+		{providerID: "k3s://node-1", expected: ""},
+		{providerID: "aws:///us-east-1a/i-0123456789abcdef0", expected: ""},
+		{providerID: "", expected: ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, clusterNameFromProvider(test.providerID, "", test.labels), test.providerID)
+	}
+}
+
+func TestGetClusterInfoFromOpenShift(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/config.openshift.io/v1/infrastructures/cluster" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":{"infrastructureName":"ocp-x7k2p","apiServerURL":"https://api.ocp.example.com:6443"}}`))
+	}))
+	defer server.Close()
+
+	client, err := k8sclient.NewForConfig(&rest.Config{Host: server.URL})
+	require.NoError(t, err)
+
+	clusterInfo, err := getClusterInfoFromOpenShift(&Config{}, client)
+	require.NoError(t, err)
+	assert.Equal(t, ClusterInfo{
+		Name: "ocp",
+		URL:  "https://api.ocp.example.com:6443",
+	}, clusterInfo)
+
+	// clusters that are not OpenShift don't have the infrastructure resource
+	_, err = getClusterInfoFromOpenShift(&Config{}, k8sfake.NewSimpleClientset())
+	assert.Error(t, err)
+}
@@ -27,12 +27,26 @@ type Config struct {
 	KubeAdm            bool     `config:"use_kubeadm"`
 	UseRegexInclude    bool     `config:"use_regex_include"`
 	UseRegexExclude    bool     `config:"use_regex_exclude"`
+	UseGlobInclude     bool     `config:"use_glob_include"`
+	UseGlobExclude     bool     `config:"use_glob_exclude"`
 	IncludeLabels      []string `config:"include_labels"`
 	ExcludeLabels      []string `config:"exclude_labels"`
 	IncludeAnnotations []string `config:"include_annotations"`
+	ExcludeAnnotations []string `config:"exclude_annotations"`
 
 	LabelsDedot      bool `config:"labels.dedot"`
 	AnnotationsDedot bool `config:"annotations.dedot"`
+
+	// ExtendedFields adds the optional fields of the resources that support them, like the status
+	// and scheduling fields of pods or the topology, capacity and conditions fields of nodes
+	ExtendedFields bool `config:"extended_fields"`
+
+	// Cluster configures the sources of the orchestrator.cluster.* fields
+	Cluster ClusterIdentifierConfig `config:"cluster"`
+
+	// FieldPolicy is applied to all the fields generated for a resource, including labels and annotations
+	// and the metadata of related resources, like the node and namespace of pods
+	FieldPolicy FieldPolicyConfig `config:"field_policy"`
 }
 
 // AddResourceMetadataConfig allows adding config for enriching additional resources
@@ -41,6 +55,10 @@ type AddResourceMetadataConfig struct {
 	Namespace  *config.C `config:"namespace"`
 	Deployment bool      `config:"deployment"`
 	CronJob    bool      `config:"cronjob"`
+	// Workload configures the labels and annotations added to pods from the deployment, statefulset,
+	// daemonset and cronjob owning them, it is disabled when not set. Deployments and cronjobs are found
+	// through the replicasets and jobs owning pods, so it doesn't require Deployment or CronJob to be set
+	Workload *config.C `config:"workload"`
 }
 
 // InitDefaults initializes the defaults for the config.
@@ -57,6 +75,15 @@ func (c *Config) Unmarshal(cfg *config.C) error {
 	return cfg.Unpack(c)
 }
 
+// Validate checks that the label and annotation patterns and the field policy can be compiled,
+// and that the cluster identifier sources exist
+func (c *Config) Validate() error {
+	if _, err := newResourceMatchers(c); err != nil {
+		return err
+	}
+	return c.Cluster.validate()
+}
+
 func GetDefaultResourceMetadataConfig() *AddResourceMetadataConfig {
 	metaConfig := Config{}
 	metaConfig.InitDefaults()
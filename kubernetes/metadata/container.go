@@ -0,0 +1,184 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-autodiscover/utils"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// digestRegexp matches image digests as defined by the OCI image spec, like sha256:<hex>
+var digestRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*:[a-fA-F0-9]{32,}$`)
+
+// ContainerMetaGen allows creation of metadata from the containers of a pod,
+// as returned by kubernetes.GetContainersInPod.
+type ContainerMetaGen interface {
+	// Generate generates metadata for a given container.
+	// Metadata map is formed in the following format:
+	// {
+	//    "kubernetes": GenerateK8s(),
+	//    "container": GenerateECS(),
+	// }
+	Generate(*kubernetes.ContainerInPod, ...FieldOptions) mapstr.M
+	// GenerateK8s generates kubernetes metadata for a given container
+	GenerateK8s(*kubernetes.ContainerInPod, ...FieldOptions) mapstr.M
+	// GenerateECS generates ECS metadata for a given container
+	GenerateECS(*kubernetes.ContainerInPod) mapstr.M
+}
+
+type container struct{}
+
+// NewContainerMetadataGenerator creates a metagen for the containers of a pod
+func NewContainerMetadataGenerator() ContainerMetaGen {
+	return &container{}
+}
+
+// Generate generates container metadata
+// Metadata map is in the following form:
+//
+//	{
+//		"kubernetes": {"container": {}},
+//		"container": {}
+//	}
+func (c *container) Generate(ctr *kubernetes.ContainerInPod, opts ...FieldOptions) mapstr.M {
+	ecsFields := c.GenerateECS(ctr)
+	meta := mapstr.M{
+		"kubernetes": c.GenerateK8s(ctr, opts...),
+	}
+	meta.DeepUpdate(ecsFields)
+	return meta
+}
+
+// GenerateECS generates the ECS container.* fields of a container
+func (c *container) GenerateECS(ctr *kubernetes.ContainerInPod) mapstr.M {
+	if ctr == nil {
+		return nil
+	}
+
+	ecsMeta := mapstr.M{}
+	_, _ = ecsMeta.Put("container.name", ctr.Spec.Name)
+	if ctr.ID != "" {
+		_, _ = ecsMeta.Put("container.id", ctr.ID)
+	}
+	if ctr.Runtime != "" {
+		_, _ = ecsMeta.Put("container.runtime", ctr.Runtime)
+	}
+	if ctr.Spec.Image != "" {
+		_, _ = ecsMeta.Put("container.image.name", ctr.Spec.Image)
+	}
+	if digest := imageDigest(ctr.Status.ImageID); digest != "" {
+		_, _ = ecsMeta.Put("container.image.hash.all", []string{digest})
+	}
+	return ecsMeta
+}
+
+// GenerateK8s generates the kubernetes container.* fields of a container
+func (c *container) GenerateK8s(ctr *kubernetes.ContainerInPod, opts ...FieldOptions) mapstr.M {
+	if ctr == nil {
+		return nil
+	}
+
+	fields := mapstr.M{
+		"name": ctr.Spec.Name,
+	}
+	if ctr.ID != "" {
+		fields["id"] = ctr.ID
+	}
+	if ctr.Runtime != "" {
+		fields["runtime"] = ctr.Runtime
+	}
+	if ctr.Spec.Image != "" {
+		fields["image"] = ctr.Spec.Image
+	}
+	if digest := imageDigest(ctr.Status.ImageID); digest != "" {
+		fields["image_digest"] = digest
+	}
+
+	// The status is not known until the container is created
+	if ctr.Status.Name != "" {
+		fields["restart_count"] = ctr.Status.RestartCount
+		if state, reason := containerState(ctr.Status.State); state != "" {
+			fields["state"] = state
+			if reason != "" {
+				fields["reason"] = reason
+			}
+		}
+	}
+
+	resources := mapstr.M{}
+	if requests := resourceListMap(ctr.Spec.Resources.Requests); len(requests) != 0 {
+		resources["requests"] = requests
+	}
+	if limits := resourceListMap(ctr.Spec.Resources.Limits); len(limits) != 0 {
+		resources["limits"] = limits
+	}
+	if len(resources) != 0 {
+		fields["resources"] = resources
+	}
+
+	meta := mapstr.M{
+		"container": fields,
+	}
+	for _, option := range opts {
+		option(meta)
+	}
+	return meta
+}
+
+// containerState returns the state of a container and the reason of the state if any
+func containerState(state v1.ContainerState) (string, string) {
+	switch {
+	case state.Running != nil:
+		return "running", ""
+	case state.Waiting != nil:
+		return "waiting", state.Waiting.Reason
+	case state.Terminated != nil:
+		return "terminated", state.Terminated.Reason
+	}
+	return "", ""
+}
+
+// resourceListMap returns the quantities of a resource list keyed by the dedotted resource name
+func resourceListMap(resources v1.ResourceList) mapstr.M {
+	out := mapstr.M{}
+	for name, quantity := range resources {
+		out[utils.DeDot(string(name))] = quantity.String()
+	}
+	return out
+}
+
+// imageDigest returns the digest of the image of a container from the image ID reported in
+// its status, like docker-pullable://nginx@sha256:<hex> or sha256:<hex>
+func imageDigest(imageID string) string {
+	if i := strings.Index(imageID, "://"); i >= 0 {
+		imageID = imageID[i+3:]
+	}
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		imageID = imageID[i+1:]
+	}
+	if digestRegexp.MatchString(imageID) {
+		return imageID
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const digest = "sha256:8b2f3e5a6d7c9f0e1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f"
+
+func TestContainer_Generate(t *testing.T) {
+	pod := &kubernetes.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx",
+			Namespace: defaultNs,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:  "nginx",
+					Image: "nginx:1.25",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    k8sresource.MustParse("100m"),
+							v1.ResourceMemory: k8sresource.MustParse("64Mi"),
+						},
+						Limits: v1.ResourceList{
+							v1.ResourceMemory:                 k8sresource.MustParse("128Mi"),
+							v1.ResourceName("nvidia.com/gpu"): k8sresource.MustParse("1"),
+						},
+					},
+				},
+			},
+			InitContainers: []v1.Container{
+				{
+					Name:  "init",
+					Image: "busybox",
+				},
+			},
+		},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{
+				{
+					Name:         "nginx",
+					ContainerID:  "containerd://abc",
+					ImageID:      "docker.io/library/nginx@" + digest,
+					RestartCount: 2,
+					State: v1.ContainerState{
+						Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+					},
+				},
+			},
+		},
+	}
+
+	containers := kubernetes.GetContainersInPod(pod)
+	require.Len(t, containers, 2)
+
+	metagen := NewContainerMetadataGenerator()
+	assert.Equal(t, mapstr.M{
+		"kubernetes": mapstr.M{
+			"container": mapstr.M{
+				"name":          "nginx",
+				"id":            "abc",
+				"runtime":       "containerd",
+				"image":         "nginx:1.25",
+				"image_digest":  digest,
+				"restart_count": int32(2),
+				"state":         "waiting",
+				"reason":        "CrashLoopBackOff",
+				"resources": mapstr.M{
+					"requests": mapstr.M{
+						"cpu":    "100m",
+						"memory": "64Mi",
+					},
+					"limits": mapstr.M{
+						"memory":         "128Mi",
+						"nvidia_com/gpu": "1",
+					},
+				},
+			},
+		},
+		"container": mapstr.M{
+			"name":    "nginx",
+			"id":      "abc",
+			"runtime": "containerd",
+			"image": mapstr.M{
+				"name": "nginx:1.25",
+				"hash": mapstr.M{
+					"all": []string{digest},
+				},
+			},
+		},
+	}, metagen.Generate(containers[0]))
+
+	// containers without status only have the fields of their spec
+	assert.Equal(t, mapstr.M{
+		"container": mapstr.M{
+			"name":  "init",
+			"image": "busybox",
+		},
+	}, metagen.GenerateK8s(containers[1]))
+	assert.Nil(t, metagen.GenerateK8s(nil))
+}
+
+func TestImageDigest(t *testing.T) {
+	tests := map[string]string{
+		"docker-pullable://nginx@" + digest: digest,
+		"docker.io/library/nginx@" + digest: digest,
+		digest:                              digest,
+		"docker://" + digest:                digest,
+		"nginx:1.25":                        "",
+		"":                                  "",
+	}
+	for imageID, expected := range tests {
+		assert.Equal(t, expected, imageDigest(imageID), imageID)
+	}
+}
@@ -0,0 +1,130 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+type cronjob struct {
+	store    cache.Store
+	resource *Resource
+}
+
+// NewCronJobMetadataGenerator creates a metagen for cronjob resources
+func NewCronJobMetadataGenerator(cfg *config.C, cronjobs cache.Store, client k8s.Interface) (MetaGen, error) {
+	resource, err := NewResourceMetadataGenerator(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cronjob{
+		resource: resource,
+		store:    cronjobs,
+	}, nil
+}
+
+// Generate generates cronjob metadata from a resource object
+// Metadata map is in the following form:
+//
+//	{
+//		  "kubernetes": {},
+//	   "some.ecs.field": "asdf"
+//	}
+//
+// All Kubernetes fields that need to be stored under kubernetes. prefix are populated by
+// GenerateK8s method while fields that are part of ECS are generated by GenerateECS method
+func (cj *cronjob) Generate(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	ecsFields := cj.GenerateECS(obj)
+	meta := mapstr.M{
+		"kubernetes": cj.GenerateK8s(obj, opts...),
+	}
+	meta.DeepUpdate(ecsFields)
+	return meta
+}
+
+// GenerateECS generates cronjob ECS metadata from a resource object
+func (cj *cronjob) GenerateECS(obj kubernetes.Resource) mapstr.M {
+	return cj.resource.GenerateECS(obj)
+}
+
+// GenerateK8s generates cronjob metadata from a resource object
+func (cj *cronjob) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	return cj.resource.finishK8s(cj.generateK8s(obj), opts...)
+}
+
+// generateK8s generates cronjob metadata from a resource object, without applying the field policy and the options
+func (cj *cronjob) generateK8s(obj kubernetes.Resource) mapstr.M {
+	_, ok := obj.(metav1.Object)
+	if !ok {
+		return nil
+	}
+
+	out := cj.resource.generateK8s("cronjob", obj)
+
+	// Metadata-only resources don't have a spec nor a status
+	cron, ok := obj.(*kubernetes.CronJob)
+	if !ok {
+		return out
+	}
+
+	_, _ = out.Put("cronjob.schedule", cron.Spec.Schedule)
+	if cron.Spec.ConcurrencyPolicy != "" {
+		_, _ = out.Put("cronjob.concurrency_policy", string(cron.Spec.ConcurrencyPolicy))
+	}
+	suspended := cron.Spec.Suspend != nil && *cron.Spec.Suspend
+	_, _ = out.Put("cronjob.suspended", suspended)
+	_, _ = out.Put("cronjob.active", len(cron.Status.Active))
+	addSelectors(out, cron.Spec.JobTemplate.Spec.Selector, cj.resource.config.LabelsDedot)
+
+	return out
+}
+
+// GenerateFromName generates cronjob metadata from a cronjob name
+func (cj *cronjob) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
+	if cj.store == nil {
+		return nil
+	}
+
+	if obj, ok, _ := cj.store.GetByKey(name); ok {
+		res, ok := obj.(kubernetes.Resource)
+		if !ok {
+			return nil
+		}
+
+		return cj.GenerateK8s(res, opts...)
+	}
+
+	return nil
+}
+
+// generateNestedFromName generates cronjob metadata from a cronjob name to embed it in the metadata of other
+// resources, without applying the field policy
+func (cj *cronjob) generateNestedFromName(name string, opts ...FieldOptions) mapstr.M {
+	obj, ok := getFromStore(cj.store, name)
+	if !ok {
+		return nil
+	}
+	return applyFieldOptions(cj.generateK8s(obj), opts)
+}
@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestCronJob_Generate(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	suspend := true
+	input := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+			Labels: map[string]string{
+				"foo": "bar",
+			},
+		},
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CronJob",
+			APIVersion: "batch/v1",
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          "*/5 * * * *",
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			Suspend:           &suspend,
+		},
+		Status: batchv1.CronJobStatus{
+			Active: []v1.ObjectReference{{Kind: "Job", Name: "obj-28000000"}},
+		},
+	}
+
+	cronjobs := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, cronjobs.Add(input))
+	metagen, err := NewCronJobMetadataGenerator(config.NewConfig(), cronjobs, client)
+	require.NoError(t, err)
+
+	expected := mapstr.M{
+		"cronjob": mapstr.M{
+			"name":               name,
+			"uid":                uid,
+			"schedule":           "*/5 * * * *",
+			"concurrency_policy": "Forbid",
+			"suspended":          true,
+			"active":             1,
+		},
+		"labels": mapstr.M{
+			"foo": "bar",
+		},
+		"namespace": defaultNs,
+	}
+	assert.Equal(t, mapstr.M{"kubernetes": expected}, metagen.Generate(input))
+	assert.Equal(t, expected, metagen.GenerateFromName(defaultNs+"/"+name))
+}
@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+type daemonset struct {
+	store    cache.Store
+	resource *Resource
+}
+
+// NewDaemonSetMetadataGenerator creates a metagen for daemonset resources
+func NewDaemonSetMetadataGenerator(cfg *config.C, daemonsets cache.Store, client k8s.Interface) (MetaGen, error) {
+	resource, err := NewResourceMetadataGenerator(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &daemonset{
+		resource: resource,
+		store:    daemonsets,
+	}, nil
+}
+
+// Generate generates daemonset metadata from a resource object
+// Metadata map is in the following form:
+//
+//	{
+//		  "kubernetes": {},
+//	   "some.ecs.field": "asdf"
+//	}
+//
+// All Kubernetes fields that need to be stored under kubernetes. prefix are populated by
+// GenerateK8s method while fields that are part of ECS are generated by GenerateECS method
+func (ds *daemonset) Generate(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	ecsFields := ds.GenerateECS(obj)
+	meta := mapstr.M{
+		"kubernetes": ds.GenerateK8s(obj, opts...),
+	}
+	meta.DeepUpdate(ecsFields)
+	return meta
+}
+
+// GenerateECS generates daemonset ECS metadata from a resource object
+func (ds *daemonset) GenerateECS(obj kubernetes.Resource) mapstr.M {
+	return ds.resource.GenerateECS(obj)
+}
+
+// GenerateK8s generates daemonset metadata from a resource object
+func (ds *daemonset) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	return ds.resource.finishK8s(ds.generateK8s(obj), opts...)
+}
+
+// generateK8s generates daemonset metadata from a resource object, without applying the field policy and the options
+func (ds *daemonset) generateK8s(obj kubernetes.Resource) mapstr.M {
+	_, ok := obj.(metav1.Object)
+	if !ok {
+		return nil
+	}
+
+	out := ds.resource.generateK8s("daemonset", obj)
+
+	// Metadata-only resources don't have a spec nor a status
+	dms, ok := obj.(*kubernetes.DaemonSet)
+	if !ok {
+		return out
+	}
+
+	desired := dms.Status.DesiredNumberScheduled
+	addWorkloadFields(out, "daemonset", workloadReplicas{
+		desired:   &desired,
+		current:   dms.Status.CurrentNumberScheduled,
+		ready:     dms.Status.NumberReady,
+		available: dms.Status.NumberAvailable,
+		updated:   dms.Status.UpdatedNumberScheduled,
+	}, string(dms.Spec.UpdateStrategy.Type))
+	addSelectors(out, dms.Spec.Selector, ds.resource.config.LabelsDedot)
+
+	return out
+}
+
+// GenerateFromName generates daemonset metadata from a daemonset name
+func (ds *daemonset) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
+	if ds.store == nil {
+		return nil
+	}
+
+	if obj, ok, _ := ds.store.GetByKey(name); ok {
+		res, ok := obj.(kubernetes.Resource)
+		if !ok {
+			return nil
+		}
+
+		return ds.GenerateK8s(res, opts...)
+	}
+
+	return nil
+}
+
+// generateNestedFromName generates daemonset metadata from a daemonset name to embed it in the metadata of other
+// resources, without applying the field policy
+func (ds *daemonset) generateNestedFromName(name string, opts ...FieldOptions) mapstr.M {
+	obj, ok := getFromStore(ds.store, name)
+	if !ok {
+		return nil
+	}
+	return applyFieldOptions(ds.generateK8s(obj), opts)
+}
@@ -0,0 +1,130 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+type deployment struct {
+	store    cache.Store
+	resource *Resource
+}
+
+// NewDeploymentMetadataGenerator creates a metagen for deployment resources
+func NewDeploymentMetadataGenerator(cfg *config.C, deployments cache.Store, client k8s.Interface) (MetaGen, error) {
+	resource, err := NewResourceMetadataGenerator(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deployment{
+		resource: resource,
+		store:    deployments,
+	}, nil
+}
+
+// Generate generates deployment metadata from a resource object
+// Metadata map is in the following form:
+//
+//	{
+//		  "kubernetes": {},
+//	   "some.ecs.field": "asdf"
+//	}
+//
+// All Kubernetes fields that need to be stored under kubernetes. prefix are populated by
+// GenerateK8s method while fields that are part of ECS are generated by GenerateECS method
+func (d *deployment) Generate(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	ecsFields := d.GenerateECS(obj)
+	meta := mapstr.M{
+		"kubernetes": d.GenerateK8s(obj, opts...),
+	}
+	meta.DeepUpdate(ecsFields)
+	return meta
+}
+
+// GenerateECS generates deployment ECS metadata from a resource object
+func (d *deployment) GenerateECS(obj kubernetes.Resource) mapstr.M {
+	return d.resource.GenerateECS(obj)
+}
+
+// GenerateK8s generates deployment metadata from a resource object
+func (d *deployment) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	return d.resource.finishK8s(d.generateK8s(obj), opts...)
+}
+
+// generateK8s generates deployment metadata from a resource object, without applying the field policy and the options
+func (d *deployment) generateK8s(obj kubernetes.Resource) mapstr.M {
+	_, ok := obj.(metav1.Object)
+	if !ok {
+		return nil
+	}
+
+	out := d.resource.generateK8s("deployment", obj)
+
+	// Metadata-only resources don't have a spec nor a status
+	dep, ok := obj.(*kubernetes.Deployment)
+	if !ok {
+		return out
+	}
+
+	addWorkloadFields(out, "deployment", workloadReplicas{
+		desired:   dep.Spec.Replicas,
+		current:   dep.Status.Replicas,
+		ready:     dep.Status.ReadyReplicas,
+		available: dep.Status.AvailableReplicas,
+		updated:   dep.Status.UpdatedReplicas,
+	}, string(dep.Spec.Strategy.Type))
+	addSelectors(out, dep.Spec.Selector, d.resource.config.LabelsDedot)
+
+	return out
+}
+
+// GenerateFromName generates deployment metadata from a deployment name
+func (d *deployment) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
+	if d.store == nil {
+		return nil
+	}
+
+	if obj, ok, _ := d.store.GetByKey(name); ok {
+		res, ok := obj.(kubernetes.Resource)
+		if !ok {
+			return nil
+		}
+
+		return d.GenerateK8s(res, opts...)
+	}
+
+	return nil
+}
+
+// generateNestedFromName generates deployment metadata from a deployment name to embed it in the metadata of other
+// resources, without applying the field policy
+func (d *deployment) generateNestedFromName(name string, opts ...FieldOptions) mapstr.M {
+	obj, ok := getFromStore(d.store, name)
+	if !ok {
+		return nil
+	}
+	return applyFieldOptions(d.generateK8s(obj), opts)
+}
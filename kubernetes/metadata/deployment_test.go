@@ -0,0 +1,163 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestDeployment_Generate(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	replicas := int32(3)
+	input := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+			Labels: map[string]string{
+				"foo": "bar",
+			},
+			Annotations: map[string]string{
+				"app": "production",
+			},
+		},
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/name": "nginx",
+				},
+			},
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RollingUpdateDeploymentStrategyType,
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas:          3,
+			ReadyReplicas:     2,
+			AvailableReplicas: 2,
+			UpdatedReplicas:   1,
+		},
+	}
+
+	cfg, err := config.NewConfigFrom(map[string]any{
+		"include_annotations": []string{"app"},
+	})
+	require.NoError(t, err)
+	metagen, err := NewDeploymentMetadataGenerator(cfg, nil, client)
+	require.NoError(t, err)
+
+	assert.Equal(t, mapstr.M{
+		"kubernetes": mapstr.M{
+			"deployment": mapstr.M{
+				"name": name,
+				"uid":  uid,
+				"replicas": mapstr.M{
+					"desired":   int32(3),
+					"current":   int32(3),
+					"ready":     int32(2),
+					"available": int32(2),
+					"updated":   int32(1),
+				},
+				"strategy": "RollingUpdate",
+			},
+			"selectors": mapstr.M{
+				"app_kubernetes_io/name": "nginx",
+			},
+			"labels": mapstr.M{
+				"foo": "bar",
+			},
+			"annotations": mapstr.M{
+				"app": "production",
+			},
+			"namespace": defaultNs,
+		},
+	}, metagen.Generate(input))
+
+	// metadata-only resources only get the common fields
+	objMeta := &metav1.PartialObjectMetadata{
+		ObjectMeta: input.ObjectMeta,
+	}
+	assert.Equal(t, mapstr.M{
+		"kubernetes": mapstr.M{
+			"deployment": mapstr.M{
+				"name": name,
+				"uid":  uid,
+			},
+			"labels": mapstr.M{
+				"foo": "bar",
+			},
+			"annotations": mapstr.M{
+				"app": "production",
+			},
+			"namespace": defaultNs,
+		},
+	}, metagen.Generate(objMeta))
+}
+
+func TestDeployment_GenerateFromName(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	input := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+			Labels: map[string]string{
+				"foo": "bar",
+			},
+		},
+	}
+
+	deployments := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, deployments.Add(input))
+	metagen, err := NewDeploymentMetadataGenerator(config.NewConfig(), deployments, client)
+	require.NoError(t, err)
+
+	assert.Equal(t, mapstr.M{
+		"deployment": mapstr.M{
+			"name": name,
+			"uid":  uid,
+			"replicas": mapstr.M{
+				"current":   int32(0),
+				"ready":     int32(0),
+				"available": int32(0),
+				"updated":   int32(0),
+			},
+		},
+		"labels": mapstr.M{
+			"foo": "bar",
+		},
+		"namespace": defaultNs,
+	}, metagen.GenerateFromName(defaultNs+"/"+name))
+	assert.Nil(t, metagen.GenerateFromName(defaultNs+"/missing"))
+}
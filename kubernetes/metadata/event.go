@@ -0,0 +1,183 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"strings"
+
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+type event struct {
+	store    cache.Store
+	resource *Resource
+	involved map[string]MetaGen
+}
+
+// NewEventMetadataGenerator creates a metagen for event resources.
+// involved maps the kind of the object an event is about, like Pod or ReplicaSet, to the metagen used
+// to enrich the event with the metadata of that object and its owners.
+func NewEventMetadataGenerator(cfg *config.C, events cache.Store, client k8s.Interface, involved map[string]MetaGen) (MetaGen, error) {
+	resource, err := NewResourceMetadataGenerator(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &event{
+		resource: resource,
+		store:    events,
+		involved: involved,
+	}, nil
+}
+
+// Generate generates event metadata from a resource object
+// Metadata map is in the following form:
+//
+//	{
+//		  "kubernetes": {},
+//	   "some.ecs.field": "asdf"
+//	}
+//
+// All Kubernetes fields that need to be stored under kubernetes. prefix are populated by
+// GenerateK8s method while fields that are part of ECS are generated by GenerateECS method
+func (e *event) Generate(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	ecsFields := e.GenerateECS(obj)
+	meta := mapstr.M{
+		"kubernetes": e.GenerateK8s(obj, opts...),
+	}
+	meta.DeepUpdate(ecsFields)
+	return meta
+}
+
+// GenerateECS generates event ECS metadata from a resource object
+func (e *event) GenerateECS(obj kubernetes.Resource) mapstr.M {
+	return e.resource.GenerateECS(obj)
+}
+
+// GenerateK8s generates event metadata from a resource object
+func (e *event) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	ev, ok := obj.(*kubernetes.Event)
+	if !ok {
+		return nil
+	}
+
+	out := e.resource.generateK8s("event", obj)
+
+	if ev.Reason != "" {
+		_, _ = out.Put("event.reason", ev.Reason)
+	}
+	if ev.Type != "" {
+		_, _ = out.Put("event.type", ev.Type)
+	}
+
+	count := ev.Count
+	if ev.Series != nil && ev.Series.Count > count {
+		count = ev.Series.Count
+	}
+	if count != 0 {
+		_, _ = out.Put("event.count", count)
+	}
+
+	// Events reported through events.k8s.io/v1 fill the reporting fields instead of the source
+	component := ev.Source.Component
+	if component == "" {
+		component = ev.ReportingController
+	}
+	if component != "" {
+		_, _ = out.Put("event.source.component", component)
+	}
+	host := ev.Source.Host
+	if host == "" {
+		host = ev.ReportingInstance
+	}
+	if host != "" {
+		_, _ = out.Put("event.source.host", host)
+	}
+
+	involvedObject := ev.InvolvedObject
+	if involvedObject.Kind == "" || involvedObject.Name == "" {
+		return e.resource.finishK8s(out, opts...)
+	}
+
+	involved := mapstr.M{
+		"kind": involvedObject.Kind,
+		"name": involvedObject.Name,
+	}
+	if involvedObject.APIVersion != "" {
+		involved["api_version"] = involvedObject.APIVersion
+	}
+	if involvedObject.UID != "" {
+		involved["uid"] = string(involvedObject.UID)
+	}
+	_, _ = out.Put("event.involved_object", involved)
+
+	// Add the metadata of the involved object, including its owners when its metagen resolves them
+	kind := strings.ToLower(involvedObject.Kind)
+	if metaGen, ok := e.involved[involvedObject.Kind]; ok && metaGen != nil {
+		key := involvedObject.Name
+		if involvedObject.Namespace != "" {
+			key = involvedObject.Namespace + "/" + involvedObject.Name
+		}
+		if meta := generateNestedFromName(metaGen, key, WithMetadata(kind)); meta != nil {
+			addInvolvedObjectMetadata(out, meta, kind)
+			return e.resource.finishK8s(out, opts...)
+		}
+	}
+	_, _ = out.Put(kind+".name", involvedObject.Name)
+
+	return e.resource.finishK8s(out, opts...)
+}
+
+// addInvolvedObjectMetadata adds the fields of the object an event is about, like pod.* or deployment.*, to the
+// metadata of the event without overwriting its fields. The labels and annotations of the object are added under
+// its kind, and the fields of its namespace are only added for events about namespaces, so they aren't mixed with
+// the ones of the event.
+func addInvolvedObjectMetadata(out, meta mapstr.M, kind string) {
+	for key, value := range meta {
+		switch {
+		case key == "labels" || key == "annotations":
+			continue
+		case kind != "namespace" && (key == "namespace" || strings.HasPrefix(key, "namespace_")):
+			continue
+		}
+		out.DeepUpdateNoOverwrite(mapstr.M{key: value})
+	}
+}
+
+// GenerateFromName generates event metadata from an event name
+func (e *event) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
+	if e.store == nil {
+		return nil
+	}
+
+	if obj, ok, _ := e.store.GetByKey(name); ok {
+		ev, ok := obj.(*kubernetes.Event)
+		if !ok {
+			return nil
+		}
+
+		return e.GenerateK8s(ev, opts...)
+	}
+
+	return nil
+}
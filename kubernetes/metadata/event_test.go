@@ -0,0 +1,322 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestEvent_Generate(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	boolean := true
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx-pod",
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+			Labels: map[string]string{
+				"app": "nginx",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "apps",
+					Kind:       "ReplicaSet",
+					Name:       "nginx-rs",
+					UID:        "005f3b90-4b9d-12f8-acf0-31020a8409087",
+					Controller: &boolean,
+				},
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName: "testnode",
+		},
+	}
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx-rs",
+			Namespace: defaultNs,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "apps",
+					Kind:       "Deployment",
+					Name:       "nginx-deployment",
+					UID:        "005f3b90-4b9d-12f8-acf0-31020a840144",
+					Controller: &boolean,
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		input  *v1.Event
+		output mapstr.M
+		name   string
+	}{
+		{
+			name: "test event about a pod controlled by a deployment",
+			input: &v1.Event{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "nginx-pod.17a0b4b4d3b3c1f2",
+					UID:       types.UID(uid),
+					Namespace: defaultNs,
+					Labels: map[string]string{
+						"source": "kubelet",
+					},
+				},
+				InvolvedObject: v1.ObjectReference{
+					APIVersion: "v1",
+					Kind:       "Pod",
+					Name:       "nginx-pod",
+					Namespace:  defaultNs,
+					UID:        types.UID(uid),
+				},
+				Reason: "BackOff",
+				Type:   "Warning",
+				Count:  3,
+				Source: v1.EventSource{
+					Component: "kubelet",
+					Host:      "testnode",
+				},
+			},
+			output: mapstr.M{
+				"kubernetes": mapstr.M{
+					"event": mapstr.M{
+						"name":   "nginx-pod.17a0b4b4d3b3c1f2",
+						"uid":    uid,
+						"reason": "BackOff",
+						"type":   "Warning",
+						"count":  int32(3),
+						"source": mapstr.M{
+							"component": "kubelet",
+							"host":      "testnode",
+						},
+						"involved_object": mapstr.M{
+							"api_version": "v1",
+							"kind":        "Pod",
+							"name":        "nginx-pod",
+							"uid":         uid,
+						},
+					},
+					// The labels of the pod don't merge with the labels of the event
+					"pod": mapstr.M{
+						"name": "nginx-pod",
+						"uid":  uid,
+						"labels": mapstr.M{
+							"app": "nginx",
+						},
+					},
+					"replicaset": mapstr.M{
+						"name": "nginx-rs",
+					},
+					"deployment": mapstr.M{
+						"name": "nginx-deployment",
+					},
+					"node": mapstr.M{
+						"name": "testnode",
+					},
+					"labels": mapstr.M{
+						"source": "kubelet",
+					},
+					"namespace": defaultNs,
+				},
+			},
+		},
+		{
+			name: "test events.k8s.io event about an unknown object",
+			input: &v1.Event{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "nginx-hpa.17a0b4b4d3b3c1f2",
+					UID:       types.UID(uid),
+					Namespace: defaultNs,
+				},
+				InvolvedObject: v1.ObjectReference{
+					Kind:      "HorizontalPodAutoscaler",
+					Name:      "nginx-hpa",
+					Namespace: defaultNs,
+				},
+				Reason:              "SuccessfulRescale",
+				Type:                "Normal",
+				Count:               1,
+				Series:              &v1.EventSeries{Count: 4},
+				ReportingController: "horizontal-pod-autoscaler",
+			},
+			output: mapstr.M{
+				"kubernetes": mapstr.M{
+					"event": mapstr.M{
+						"name":   "nginx-hpa.17a0b4b4d3b3c1f2",
+						"uid":    uid,
+						"reason": "SuccessfulRescale",
+						"type":   "Normal",
+						"count":  int32(4),
+						"source": mapstr.M{
+							"component": "horizontal-pod-autoscaler",
+						},
+						"involved_object": mapstr.M{
+							"kind": "HorizontalPodAutoscaler",
+							"name": "nginx-hpa",
+						},
+					},
+					"horizontalpodautoscaler": mapstr.M{
+						"name": "nginx-hpa",
+					},
+					"namespace": defaultNs,
+				},
+			},
+		},
+	}
+
+	cfg := config.NewConfig()
+	pods := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, pods.Add(pod))
+	replicasets := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, replicasets.Add(rs))
+	rsMeta, err := NewReplicasetMetadataGenerator(cfg, replicasets, client)
+	require.NoError(t, err)
+	podMeta, err := NewPodMetadataGenerator(cfg, pods, client, nil, nil, rsMeta, nil, &AddResourceMetadataConfig{Deployment: true})
+	require.NoError(t, err)
+
+	metagen, err := NewEventMetadataGenerator(cfg, nil, client, map[string]MetaGen{
+		"Pod":        podMeta,
+		"ReplicaSet": rsMeta,
+	})
+	require.NoError(t, err)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.output, metagen.Generate(test.input))
+		})
+	}
+}
+
+func TestEvent_GenerateFromName(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	input := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+		},
+		InvolvedObject: v1.ObjectReference{
+			Kind: "Node",
+			Name: "testnode",
+		},
+		Reason: "NodeReady",
+		Type:   "Normal",
+	}
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "testnode",
+			UID:  types.UID(uid),
+		},
+		Status: v1.NodeStatus{
+			Addresses: []v1.NodeAddress{{Type: v1.NodeHostName, Address: "node1"}},
+		},
+	}
+
+	cfg := config.NewConfig()
+	events := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, events.Add(input))
+	nodes := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, nodes.Add(node))
+
+	nodeMeta, err := NewNodeMetadataGenerator(cfg, nodes, client)
+	require.NoError(t, err)
+	metagen, err := NewEventMetadataGenerator(cfg, events, client, map[string]MetaGen{
+		"Node": nodeMeta,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{
+		"event": mapstr.M{
+			"name":   name,
+			"uid":    uid,
+			"reason": "NodeReady",
+			"type":   "Normal",
+			"involved_object": mapstr.M{
+				"kind": "Node",
+				"name": "testnode",
+			},
+		},
+		"node": mapstr.M{
+			"name":     "testnode",
+			"uid":      uid,
+			"hostname": "node1",
+		},
+		"namespace": defaultNs,
+	}, metagen.GenerateFromName(defaultNs+"/"+name))
+	assert.Nil(t, metagen.GenerateFromName("missing"))
+
+	// non event resources are ignored
+	var res kubernetes.Resource = node
+	assert.Nil(t, metagen.GenerateK8s(res))
+}
+
+func TestAddInvolvedObjectMetadata(t *testing.T) {
+	event := mapstr.M{
+		"event":     mapstr.M{"name": "nginx-pod.17a0b4b4d3b3c1f2"},
+		"namespace": defaultNs,
+		"labels":    mapstr.M{"source": "kubelet"},
+	}
+	pod := mapstr.M{
+		"pod":              mapstr.M{"name": "nginx-pod", "labels": mapstr.M{"app": "nginx"}},
+		"event":            mapstr.M{"name": "other"},
+		"namespace":        "other",
+		"namespace_uid":    "namespace-uid",
+		"namespace_labels": mapstr.M{"team": "web"},
+		"labels":           mapstr.M{"app": "nginx"},
+		"annotations":      mapstr.M{"owner": "sre"},
+		"node":             mapstr.M{"name": "testnode"},
+	}
+
+	out := event.Clone()
+	addInvolvedObjectMetadata(out, pod, "pod")
+	assert.Equal(t, mapstr.M{
+		"event":     mapstr.M{"name": "nginx-pod.17a0b4b4d3b3c1f2"},
+		"namespace": defaultNs,
+		"labels":    mapstr.M{"source": "kubelet"},
+		"pod":       mapstr.M{"name": "nginx-pod", "labels": mapstr.M{"app": "nginx"}},
+		"node":      mapstr.M{"name": "testnode"},
+	}, out)
+
+	// The fields of the namespace are added for events about namespaces
+	out = event.Clone()
+	addInvolvedObjectMetadata(out, mapstr.M{
+		"namespace":        defaultNs,
+		"namespace_uid":    "namespace-uid",
+		"namespace_labels": mapstr.M{"team": "web"},
+	}, "namespace")
+	assert.Equal(t, mapstr.M{
+		"event":            mapstr.M{"name": "nginx-pod.17a0b4b4d3b3c1f2"},
+		"namespace":        defaultNs,
+		"namespace_uid":    "namespace-uid",
+		"namespace_labels": mapstr.M{"team": "web"},
+		"labels":           mapstr.M{"source": "kubelet"},
+	}, out)
+}
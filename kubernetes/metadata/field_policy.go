@@ -0,0 +1,195 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/safemapstr"
+)
+
+// RedactedValue replaces the values of the fields redacted by the field policy
+const RedactedValue = "REDACTED"
+
+// FieldPolicyConfig declares the policy applied to the fields generated for a resource, including its labels
+// and annotations. Fields are referenced by their key in the generated metadata, without the kubernetes. prefix,
+// like pod.uid, labels.app or annotations.example_com/secret when annotations are dedotted. A field also matches
+// all the fields under it, so annotations matches all the annotations.
+// Rules are applied in the following order: include, exclude, redact, hash, max_length and rename.
+type FieldPolicyConfig struct {
+	// Include keeps only the matching fields when set
+	Include []string `config:"include"`
+	// Exclude drops the matching fields
+	Exclude []string `config:"exclude"`
+	// Redact replaces the value of the matching fields with RedactedValue
+	Redact []string `config:"redact"`
+	// Hash replaces the value of the matching fields with its SHA-256 hex digest
+	Hash []string `config:"hash"`
+	// UseRegex makes the fields of include, exclude, redact and hash regular expressions
+	UseRegex bool `config:"use_regex"`
+	// MaxLength truncates string values longer than it, it is disabled when not positive
+	MaxLength int `config:"max_length"`
+	// Rename moves fields, and the fields under them, to a new key
+	Rename []FieldRename `config:"rename"`
+}
+
+// FieldRename renames the field From to To
+type FieldRename struct {
+	From string `config:"from"`
+	To   string `config:"to"`
+}
+
+type fieldMatcher struct {
+	fields   []string
+	patterns []*regexp.Regexp
+}
+
+// fieldPolicy is the compiled form of a FieldPolicyConfig
+type fieldPolicy struct {
+	include   *fieldMatcher
+	exclude   *fieldMatcher
+	redact    *fieldMatcher
+	hash      *fieldMatcher
+	maxLength int
+	rename    []FieldRename
+}
+
+// newFieldPolicy compiles the policy, it returns nil if the policy is empty
+func newFieldPolicy(cfg FieldPolicyConfig) (*fieldPolicy, error) {
+	if len(cfg.Include) == 0 && len(cfg.Exclude) == 0 && len(cfg.Redact) == 0 && len(cfg.Hash) == 0 &&
+		cfg.MaxLength <= 0 && len(cfg.Rename) == 0 {
+		return nil, nil
+	}
+
+	p := &fieldPolicy{
+		maxLength: cfg.MaxLength,
+		rename:    cfg.Rename,
+	}
+	for _, matcher := range []struct {
+		setting string
+		fields  []string
+		target  **fieldMatcher
+	}{
+		{"include", cfg.Include, &p.include},
+		{"exclude", cfg.Exclude, &p.exclude},
+		{"redact", cfg.Redact, &p.redact},
+		{"hash", cfg.Hash, &p.hash},
+	} {
+		m, err := newFieldMatcher(matcher.fields, cfg.UseRegex)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", matcher.setting, err)
+		}
+		*matcher.target = m
+	}
+	return p, nil
+}
+
+func newFieldMatcher(fields []string, useRegex bool) (*fieldMatcher, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	if !useRegex {
+		return &fieldMatcher{fields: fields}, nil
+	}
+
+	m := &fieldMatcher{}
+	for _, field := range fields {
+		pattern, err := regexp.Compile(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", field, err)
+		}
+		m.patterns = append(m.patterns, pattern)
+	}
+	return m, nil
+}
+
+// match returns true if key is one of the fields, or is under one of them
+func (m *fieldMatcher) match(key string) bool {
+	if m == nil {
+		return false
+	}
+	for _, field := range m.fields {
+		if isFieldOrUnder(key, field) {
+			return true
+		}
+	}
+	for _, pattern := range m.patterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the metadata resulting from applying the policy to meta
+func (p *fieldPolicy) apply(meta mapstr.M) mapstr.M {
+	if p == nil || meta == nil {
+		return meta
+	}
+
+	out := mapstr.M{}
+	for key, value := range meta.Flatten() {
+		if p.include != nil && !p.include.match(key) {
+			continue
+		}
+		if p.exclude.match(key) {
+			continue
+		}
+
+		switch {
+		case p.redact.match(key):
+			value = RedactedValue
+		case p.hash.match(key):
+			sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+			value = hex.EncodeToString(sum[:])
+		}
+
+		if s, ok := value.(string); ok && p.maxLength > 0 && len(s) > p.maxLength {
+			value = truncate(s, p.maxLength)
+		}
+
+		for _, rename := range p.rename {
+			if isFieldOrUnder(key, rename.From) {
+				key = rename.To + strings.TrimPrefix(key, rename.From)
+				break
+			}
+		}
+
+		_ = safemapstr.Put(out, key, value)
+	}
+	return out
+}
+
+// truncate returns the longest prefix of s up to maxLength bytes that doesn't split a character
+func truncate(s string, maxLength int) string {
+	n := maxLength
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func isFieldOrUnder(key, field string) bool {
+	return key == field || strings.HasPrefix(key, field+".")
+}
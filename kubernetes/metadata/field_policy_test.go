@@ -0,0 +1,229 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestResource_GenerateWithFieldPolicy(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+			Labels: map[string]string{
+				"app":   "nginx",
+				"owner": "jane@example.com",
+			},
+			Annotations: map[string]string{
+				"example.com/token":                                "s3cr3t",
+				"example.com/description":                          "a long description",
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+	}
+	ownerHash := sha256.Sum256([]byte("jane@example.com"))
+
+	tests := []struct {
+		name   string
+		config map[string]any
+		output mapstr.M
+	}{
+		{
+			name: "exclude, redact, hash, max length and rename",
+			config: map[string]any{
+				"include_annotations": []string{"example.com/token", "example.com/description", "kubectl.kubernetes.io/last-applied-configuration"},
+				"exclude_annotations": []string{"kubectl.kubernetes.io/last-applied-configuration"},
+				"field_policy": map[string]any{
+					"exclude":    []string{"pod.uid"},
+					"redact":     []string{"annotations.example_com/token"},
+					"hash":       []string{"labels.owner"},
+					"max_length": 10,
+					"rename": []map[string]string{
+						{"from": "labels", "to": "pod_labels"},
+					},
+				},
+			},
+			output: mapstr.M{
+				"pod": mapstr.M{
+					"name": name,
+				},
+				"namespace": defaultNs,
+				"pod_labels": mapstr.M{
+					"app":   "nginx",
+					"owner": hex.EncodeToString(ownerHash[:])[:10],
+				},
+				"annotations": mapstr.M{
+					"example_com/token":       RedactedValue,
+					"example_com/description": "a long des",
+				},
+			},
+		},
+		{
+			name: "include with regex",
+			config: map[string]any{
+				"field_policy": map[string]any{
+					"include":   []string{"^pod\\.name$", "^labels\\.a"},
+					"use_regex": true,
+				},
+			},
+			output: mapstr.M{
+				"pod": mapstr.M{
+					"name": name,
+				},
+				"labels": mapstr.M{
+					"app": "nginx",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := config.NewConfigFrom(test.config)
+			require.NoError(t, err)
+			metagen, err := NewResourceMetadataGenerator(cfg, client)
+			require.NoError(t, err)
+			assert.Equal(t, test.output, metagen.GenerateK8s("pod", pod))
+		})
+	}
+}
+
+func TestPod_GenerateWithFieldPolicy(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+		},
+		Spec: v1.PodSpec{
+			NodeName: "testnode",
+		},
+		Status: v1.PodStatus{
+			PodIP: "10.0.0.1",
+		},
+	}
+
+	// The policy also applies to the fields added by the pod metagen, like pod.ip and node.name
+	cfg, err := config.NewConfigFrom(map[string]any{
+		"field_policy": map[string]any{
+			"exclude": []string{"pod.uid", "pod.ip"},
+			"redact":  []string{"node.name"},
+		},
+	})
+	require.NoError(t, err)
+	metagen, err := NewPodMetadataGenerator(cfg, nil, client, nil, nil, nil, nil, &AddResourceMetadataConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{
+		"pod": mapstr.M{
+			"name": name,
+		},
+		"namespace": defaultNs,
+		"node": mapstr.M{
+			"name": RedactedValue,
+		},
+	}, metagen.GenerateK8s(pod))
+}
+
+func TestEvent_GenerateWithFieldPolicy(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "testnode",
+			UID:  types.UID("node-uid"),
+		},
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+		},
+		Spec: v1.PodSpec{
+			NodeName: node.Name,
+		},
+	}
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + ".17a0b4b4d3b3c1f2",
+			UID:       types.UID("event-uid"),
+			Namespace: defaultNs,
+		},
+		InvolvedObject: v1.ObjectReference{
+			Kind:      "Pod",
+			Name:      name,
+			Namespace: defaultNs,
+		},
+	}
+	nodes := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, nodes.Add(node))
+	pods := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, pods.Add(pod))
+
+	// All the metagens share the policy, the fields embedded from other metagens are only hashed once
+	cfg, err := config.NewConfigFrom(map[string]any{
+		"field_policy": map[string]any{
+			"hash": []string{"pod.uid", "node.uid"},
+		},
+	})
+	require.NoError(t, err)
+	nodeMetaGen, err := NewNodeMetadataGenerator(cfg, nodes, client)
+	require.NoError(t, err)
+	podMetaGen, err := NewPodMetadataGenerator(cfg, pods, client, nodeMetaGen, nil, nil, nil, &AddResourceMetadataConfig{})
+	require.NoError(t, err)
+	eventMetaGen, err := NewEventMetadataGenerator(cfg, nil, client, map[string]MetaGen{"Pod": podMetaGen})
+	require.NoError(t, err)
+
+	podHash := sha256.Sum256([]byte(uid))
+	nodeHash := sha256.Sum256([]byte("node-uid"))
+	meta := eventMetaGen.GenerateK8s(event)
+	podUID, err := meta.GetValue("pod.uid")
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(podHash[:]), podUID)
+	nodeUID, err := meta.GetValue("node.uid")
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(nodeHash[:]), nodeUID)
+
+	// The event has the same hashes as the pod
+	podMeta := podMetaGen.GenerateK8s(pod)
+	assert.Equal(t, podMeta["pod"], meta["pod"])
+	assert.Equal(t, podMeta["node"], meta["node"])
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abcdef", 3))
+	// multi-byte characters are not split
+	assert.Equal(t, "h", truncate("hé", 2))
+	assert.Equal(t, "hé", truncate("hé!", 3))
+}
@@ -33,11 +33,16 @@ type job struct {
 }
 
 // NewJobMetadataGenerator creates a metagen for job resources
-func NewJobMetadataGenerator(cfg *config.C, jobs cache.Store, client k8s.Interface) MetaGen {
+func NewJobMetadataGenerator(cfg *config.C, jobs cache.Store, client k8s.Interface) (MetaGen, error) {
+	resource, err := NewResourceMetadataGenerator(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
 	return &job{
-		resource: NewResourceMetadataGenerator(cfg, client),
+		resource: resource,
 		store:    jobs,
-	}
+	}, nil
 }
 
 // Generate generates job metadata from a resource object
@@ -92,3 +97,16 @@ func (jb *job) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
 
 	return nil
 }
+
+// generateNestedFromName generates job metadata from a job name to embed it in the metadata of other
+// resources, without applying the field policy
+func (jb *job) generateNestedFromName(name string, opts ...FieldOptions) mapstr.M {
+	obj, ok := getFromStore(jb.store, name)
+	if !ok {
+		return nil
+	}
+	if _, ok := obj.(metav1.Object); !ok {
+		return nil
+	}
+	return applyFieldOptions(jb.resource.generateK8s("job", obj), opts)
+}
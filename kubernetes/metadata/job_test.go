@@ -88,7 +88,8 @@ func TestJob_Generate(t *testing.T) {
 	}
 
 	cfg := config.NewConfig()
-	metagen := NewJobMetadataGenerator(cfg, nil, client)
+	metagen, err := NewJobMetadataGenerator(cfg, nil, client)
+	require.NoError(t, err)
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equal(t, test.output, metagen.Generate(test.input))
@@ -191,7 +192,8 @@ func TestJob_GenerateFromName(t *testing.T) {
 		jobs := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err := jobs.Add(test.input)
 		require.NoError(t, err)
-		metagen := NewJobMetadataGenerator(cfg, jobs, client)
+		metagen, err := NewJobMetadataGenerator(cfg, jobs, client)
+		require.NoError(t, err)
 
 		accessor, err := meta.Accessor(test.input)
 		require.NoError(t, err)
@@ -0,0 +1,177 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/elastic/elastic-agent-autodiscover/utils"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/safemapstr"
+)
+
+// matchMode is how the keys of a keyMatcher are matched
+type matchMode int
+
+const (
+	matchExact matchMode = iota
+	matchRegex
+	matchGlob
+)
+
+// newMatchMode returns the match mode of the use_regex_* and use_glob_* settings
+func newMatchMode(useRegex, useGlob bool) (matchMode, error) {
+	switch {
+	case useRegex && useGlob:
+		return matchExact, errors.New("regex and glob matching cannot be enabled at the same time")
+	case useRegex:
+		return matchRegex, nil
+	case useGlob:
+		return matchGlob, nil
+	}
+	return matchExact, nil
+}
+
+// keyMatcher selects the entries of labels or annotations maps by key.
+// Patterns are compiled once, when the matcher is created.
+type keyMatcher struct {
+	keys     []string
+	patterns []*regexp.Regexp
+}
+
+// newKeyMatcher creates a matcher for the given keys, that are regular expressions or glob patterns
+// depending on mode. Regular expressions match anywhere in the key while glob patterns match the
+// whole key, * matches any sequence of characters and ? any single character.
+func newKeyMatcher(keys []string, mode matchMode) (*keyMatcher, error) {
+	if mode == matchExact {
+		return &keyMatcher{keys: keys}, nil
+	}
+
+	m := &keyMatcher{}
+	for _, key := range keys {
+		expr := key
+		if mode == matchGlob {
+			expr = globToRegexp(key)
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", key, err)
+		}
+		m.patterns = append(m.patterns, pattern)
+	}
+	return m, nil
+}
+
+// globToRegexp returns the anchored regular expression of a glob pattern
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, c := range glob {
+		switch c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return expr.String()
+}
+
+// subset returns the entries of input whose key matches
+func (m *keyMatcher) subset(input map[string]string, dedot bool) mapstr.M {
+	output := mapstr.M{}
+	if input == nil || m == nil {
+		return output
+	}
+
+	put := func(key, value string) {
+		if dedot {
+			_, _ = output.Put(utils.DeDot(key), value)
+		} else {
+			_ = safemapstr.Put(output, key, value)
+		}
+	}
+
+	for _, key := range m.keys {
+		if value, ok := input[key]; ok {
+			put(key, value)
+		}
+	}
+	for _, pattern := range m.patterns {
+		for key, value := range input {
+			if pattern.MatchString(key) {
+				put(key, value)
+			}
+		}
+	}
+	return output
+}
+
+// resourceMatchers are the compiled matchers of a Config
+type resourceMatchers struct {
+	includeLabels      *keyMatcher
+	excludeLabels      *keyMatcher
+	includeAnnotations *keyMatcher
+	excludeAnnotations *keyMatcher
+	fieldPolicy        *fieldPolicy
+}
+
+// newResourceMatchers compiles the matchers of the given config
+func newResourceMatchers(c *Config) (*resourceMatchers, error) {
+	includeMode, err := newMatchMode(c.UseRegexInclude, c.UseGlobInclude)
+	if err != nil {
+		return nil, fmt.Errorf("include settings: %w", err)
+	}
+	excludeMode, err := newMatchMode(c.UseRegexExclude, c.UseGlobExclude)
+	if err != nil {
+		return nil, fmt.Errorf("exclude settings: %w", err)
+	}
+
+	m := &resourceMatchers{}
+	for _, matcher := range []struct {
+		setting string
+		keys    []string
+		mode    matchMode
+		target  **keyMatcher
+	}{
+		{"include_labels", c.IncludeLabels, includeMode, &m.includeLabels},
+		{"exclude_labels", c.ExcludeLabels, excludeMode, &m.excludeLabels},
+		{"include_annotations", c.IncludeAnnotations, includeMode, &m.includeAnnotations},
+		{"exclude_annotations", c.ExcludeAnnotations, excludeMode, &m.excludeAnnotations},
+	} {
+		if len(matcher.keys) == 0 {
+			continue
+		}
+		*matcher.target, err = newKeyMatcher(matcher.keys, matcher.mode)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", matcher.setting, err)
+		}
+	}
+
+	m.fieldPolicy, err = newFieldPolicy(c.FieldPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("field_policy: %w", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,175 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestKeyMatcher_Glob(t *testing.T) {
+	labels := map[string]string{
+		"app.kubernetes.io/name":    "nginx",
+		"app.kubernetes.io/version": "1.25",
+		"app":                       "nginx",
+		"team":                      "platform",
+		"tier":                      "web",
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected mapstr.M
+	}{
+		{
+			name: "star matches any sequence, including dots and slashes",
+			keys: []string{"app.kubernetes.io/*"},
+			expected: mapstr.M{
+				"app_kubernetes_io/name":    "nginx",
+				"app_kubernetes_io/version": "1.25",
+			},
+		},
+		{
+			name: "patterns match the whole key",
+			keys: []string{"app"},
+			expected: mapstr.M{
+				"app": "nginx",
+			},
+		},
+		{
+			name: "question mark matches a single character",
+			keys: []string{"t??r", "te?m"},
+			expected: mapstr.M{
+				"tier": "web",
+				"team": "platform",
+			},
+		},
+		{
+			name:     "regex characters are literals",
+			keys:     []string{"app.kubernetes.io/nam."},
+			expected: mapstr.M{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matcher, err := newKeyMatcher(test.keys, matchGlob)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, matcher.subset(labels, true))
+		})
+	}
+}
+
+func TestNewResourceMetadataGenerator_InvalidConfig(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	tests := map[string]map[string]any{
+		"invalid include regex": {
+			"include_labels":    []string{"app", "(unclosed"},
+			"use_regex_include": true,
+		},
+		"invalid exclude regex": {
+			"exclude_annotations": []string{"[z-a]"},
+			"use_regex_exclude":   true,
+		},
+		"regex and glob": {
+			"include_labels":    []string{"app*"},
+			"use_regex_include": true,
+			"use_glob_include":  true,
+		},
+		"invalid field policy regex": {
+			"field_policy": map[string]any{
+				"exclude":   []string{"*uid"},
+				"use_regex": true,
+			},
+		},
+	}
+
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := config.NewConfigFrom(settings)
+			require.NoError(t, err)
+
+			_, err = NewResourceMetadataGenerator(cfg, client)
+			assert.Error(t, err)
+			metagen, err := NewPodMetadataGenerator(cfg, nil, client, nil, nil, nil, nil, addResourceMetadata)
+			assert.Error(t, err)
+			assert.Nil(t, metagen)
+			pods := &workloadWatcherMock{store: cache.NewStore(cache.MetaNamespaceKeyFunc), client: client}
+			metagen, err = GetPodMetaGen(cfg, pods, nil, nil, nil, nil, addResourceMetadata)
+			assert.Error(t, err)
+			assert.Nil(t, metagen)
+
+			var c Config
+			assert.Error(t, c.Unmarshal(cfg))
+		})
+	}
+}
+
+func TestResource_GenerateWithGlob(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	cfg, err := config.NewConfigFrom(map[string]any{
+		"include_labels":      []string{"app*"},
+		"use_glob_include":    true,
+		"exclude_labels":      []string{"*/version"},
+		"use_glob_exclude":    true,
+		"include_annotations": []string{"example.com/*"},
+	})
+	require.NoError(t, err)
+
+	metagen, err := NewResourceMetadataGenerator(cfg, client)
+	require.NoError(t, err)
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":    "nginx",
+				"app.kubernetes.io/version": "1.25",
+				"team":                      "platform",
+			},
+			Annotations: map[string]string{
+				"example.com/owner": "sre",
+				"other":             "value",
+			},
+		},
+	}
+	assert.Equal(t, mapstr.M{
+		"pod": mapstr.M{
+			"name": name,
+			"uid":  uid,
+		},
+		"namespace": defaultNs,
+		"labels": mapstr.M{
+			"app_kubernetes_io/name": "nginx",
+		},
+		"annotations": mapstr.M{
+			"example_com/owner": "sre",
+		},
+	}, metagen.GenerateK8s("pod", pod))
+}
@@ -59,6 +59,7 @@ type FieldOptions func(mapstr.M)
 type ClusterInfo struct {
 	URL  string
 	Name string
+	ID   string
 }
 
 type ClusterConfiguration struct {
@@ -87,7 +88,10 @@ func WithMetadata(kind string) FieldOptions {
 }
 
 // GetPodMetaGen is a wrapper function that creates a metaGen for pod resource and has embeeded
-// nodeMetaGen and namespaceMetaGen
+// nodeMetaGen and namespaceMetaGen. Optional metagens, like the ones of the workloads, can be set with opts.
+// Workload metadata requires the watchers of the workloads, set with WithWorkloadWatcher, and the replicaset
+// and job watchers to find the deployments and cronjobs owning pods.
+// An error is returned if any of the metagens cannot be created, like when a config has an invalid pattern.
 func GetPodMetaGen(
 	cfg *config.C,
 	podWatcher kubernetes.Watcher,
@@ -95,21 +99,35 @@ func GetPodMetaGen(
 	namespaceWatcher kubernetes.Watcher,
 	replicasetWatcher kubernetes.Watcher,
 	jobWatcher kubernetes.Watcher,
-	metaConf *AddResourceMetadataConfig) MetaGen {
+	metaConf *AddResourceMetadataConfig,
+	opts ...PodMetaGenOption) (MetaGen, error) {
 	var nodeMetaGen, namespaceMetaGen, rsMetaGen, jobMetaGen MetaGen
+	var err error
 	if nodeWatcher != nil && metaConf.Node.Enabled() {
-		nodeMetaGen = NewNodeMetadataGenerator(metaConf.Node, nodeWatcher.Store(), nodeWatcher.Client())
+		nodeMetaGen, err = NewNodeMetadataGenerator(metaConf.Node, nodeWatcher.Store(), nodeWatcher.Client())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create node metagen: %w", err)
+		}
 	}
 	if namespaceWatcher != nil && metaConf.Namespace.Enabled() {
-		namespaceMetaGen = NewNamespaceMetadataGenerator(metaConf.Namespace, namespaceWatcher.Store(), namespaceWatcher.Client())
+		namespaceMetaGen, err = NewNamespaceMetadataGenerator(metaConf.Namespace, namespaceWatcher.Store(), namespaceWatcher.Client())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create namespace metagen: %w", err)
+		}
 	}
-	if replicasetWatcher != nil && metaConf.Deployment {
-		rsMetaGen = NewReplicasetMetadataGenerator(cfg, replicasetWatcher.Store(), replicasetWatcher.Client())
+	if replicasetWatcher != nil && (metaConf.Deployment || metaConf.Workload.Enabled()) {
+		rsMetaGen, err = NewReplicasetMetadataGenerator(cfg, replicasetWatcher.Store(), replicasetWatcher.Client())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create replicaset metagen: %w", err)
+		}
 	}
-	if jobWatcher != nil && metaConf.CronJob {
-		jobMetaGen = NewJobMetadataGenerator(cfg, jobWatcher.Store(), jobWatcher.Client())
+	if jobWatcher != nil && (metaConf.CronJob || metaConf.Workload.Enabled()) {
+		jobMetaGen, err = NewJobMetadataGenerator(cfg, jobWatcher.Store(), jobWatcher.Client())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create job metagen: %w", err)
+		}
 	}
-	metaGen := NewPodMetadataGenerator(
+	return NewPodMetadataGenerator(
 		cfg,
 		podWatcher.Store(),
 		podWatcher.Client(),
@@ -117,29 +135,61 @@ func GetPodMetaGen(
 		namespaceMetaGen,
 		rsMetaGen,
 		jobMetaGen,
-		metaConf)
-	return metaGen
+		metaConf,
+		opts...)
 }
 
-// GetKubernetesClusterIdentifier returns ClusterInfo for k8s if available
+// GetEventMetaGen is a wrapper function that creates a metaGen for event resources that enriches
+// events with the metadata of the pods, replicasets, jobs, nodes and namespaces they are about.
+// An error is returned if any of the metagens cannot be created, like when a config has an invalid pattern.
+func GetEventMetaGen(
+	cfg *config.C,
+	eventWatcher kubernetes.Watcher,
+	podWatcher kubernetes.Watcher,
+	nodeWatcher kubernetes.Watcher,
+	namespaceWatcher kubernetes.Watcher,
+	replicasetWatcher kubernetes.Watcher,
+	jobWatcher kubernetes.Watcher,
+	metaConf *AddResourceMetadataConfig) (MetaGen, error) {
+	involved := map[string]MetaGen{}
+	var err error
+	if podWatcher != nil {
+		if involved["Pod"], err = GetPodMetaGen(cfg, podWatcher, nodeWatcher, namespaceWatcher, replicasetWatcher, jobWatcher, metaConf); err != nil {
+			return nil, err
+		}
+	}
+	if nodeWatcher != nil && metaConf.Node.Enabled() {
+		if involved["Node"], err = NewNodeMetadataGenerator(metaConf.Node, nodeWatcher.Store(), nodeWatcher.Client()); err != nil {
+			return nil, fmt.Errorf("failed to create node metagen: %w", err)
+		}
+	}
+	if namespaceWatcher != nil && metaConf.Namespace.Enabled() {
+		if involved["Namespace"], err = NewNamespaceMetadataGenerator(metaConf.Namespace, namespaceWatcher.Store(), namespaceWatcher.Client()); err != nil {
+			return nil, fmt.Errorf("failed to create namespace metagen: %w", err)
+		}
+	}
+	if replicasetWatcher != nil {
+		if involved["ReplicaSet"], err = NewReplicasetMetadataGenerator(cfg, replicasetWatcher.Store(), replicasetWatcher.Client()); err != nil {
+			return nil, fmt.Errorf("failed to create replicaset metagen: %w", err)
+		}
+	}
+	if jobWatcher != nil {
+		if involved["Job"], err = NewJobMetadataGenerator(cfg, jobWatcher.Store(), jobWatcher.Client()); err != nil {
+			return nil, fmt.Errorf("failed to create job metagen: %w", err)
+		}
+	}
+	return NewEventMetadataGenerator(cfg, eventWatcher.Store(), eventWatcher.Client(), involved)
+}
+
+// GetKubernetesClusterIdentifier returns ClusterInfo for k8s if available, trying the sources of the
+// cluster config in order, see DefaultClusterIdentifierSources
 func GetKubernetesClusterIdentifier(cfg *config.C, client k8sclient.Interface) (ClusterInfo, error) {
-	// try with kube config file
 	var c Config
 	err := c.Unmarshal(cfg)
 	if err != nil {
 		return ClusterInfo{}, err
 	}
-	clusterInfo, err := getClusterInfoFromKubeConfigFile(c.KubeConfig)
-	if err == nil {
-		return clusterInfo, nil
-	}
-
-	// try with kubeadm-config configmap only if config_kubeAdm == true
-	clusterInfo, err = getClusterInfoFromKubeadmConfigMap(client, c.KubeAdm)
-	if err == nil {
-		return clusterInfo, nil
-	}
-	return ClusterInfo{}, fmt.Errorf("unable to retrieve cluster identifiers")
+	return getClusterInfo(&c, client)
 }
 
 func getClusterInfoFromKubeadmConfigMap(client k8sclient.Interface, kubeadm bool) (ClusterInfo, error) {
@@ -150,7 +200,9 @@ func getClusterInfoFromKubeadmConfigMap(client k8sclient.Interface, kubeadm bool
 	if !kubeadm {
 		return clusterInfo, nil
 	}
-	cm, err := client.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), "kubeadm-config", metav1.GetOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), clusterRequestTimeout)
+	defer cancel()
+	cm, err := client.CoreV1().ConfigMaps("kube-system").Get(ctx, "kubeadm-config", metav1.GetOptions{})
 	if err != nil {
 		return clusterInfo, fmt.Errorf("unable to get cluster identifiers from kubeadm-config: %w", err)
 	}
@@ -195,7 +247,7 @@ func getClusterInfoFromKubeConfigFile(kubeconfig string) (ClusterInfo, error) {
 
 	for key, element := range kubeCfg.Clusters {
 		if element.Server == cfg.Host {
-			return ClusterInfo{element.Server, key}, nil
+			return ClusterInfo{URL: element.Server, Name: key}, nil
 		}
 	}
 	return ClusterInfo{}, fmt.Errorf("unable to get cluster identifiers from kube_config")
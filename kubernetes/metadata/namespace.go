@@ -34,11 +34,16 @@ type namespace struct {
 }
 
 // NewNamespaceMetadataGenerator creates a metagen for namespace resources
-func NewNamespaceMetadataGenerator(cfg *config.C, namespaces cache.Store, client k8s.Interface) MetaGen {
+func NewNamespaceMetadataGenerator(cfg *config.C, namespaces cache.Store, client k8s.Interface) (MetaGen, error) {
+	resource, err := NewResourceMetadataGenerator(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
 	return &namespace{
-		resource: NewResourceMetadataGenerator(cfg, client),
+		resource: resource,
 		store:    namespaces,
-	}
+	}, nil
 }
 
 // Generate generates pod metadata from a resource object
@@ -97,6 +102,19 @@ func (n *namespace) GenerateFromName(name string, opts ...FieldOptions) mapstr.M
 	return nil
 }
 
+// generateNestedFromName generates namespace metadata from a namespace name to embed it in the metadata of other
+// resources, without applying the field policy
+func (n *namespace) generateNestedFromName(name string, opts ...FieldOptions) mapstr.M {
+	obj, ok := getFromStore(n.store, name)
+	if !ok {
+		return nil
+	}
+	if _, ok := obj.(*kubernetes.Namespace); !ok {
+		return nil
+	}
+	return flattenMetadata(applyFieldOptions(n.resource.generateK8s(resource, obj), opts))
+}
+
 func flattenMetadata(in mapstr.M) mapstr.M {
 	out := mapstr.M{}
 	rawFields, err := in.GetValue(resource)
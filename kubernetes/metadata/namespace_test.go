@@ -93,7 +93,8 @@ func TestNamespace_Generate(t *testing.T) {
 		t.Fatalf("Could not merge configs")
 	}
 
-	metagen := NewNamespaceMetadataGenerator(cfg, nil, client)
+	metagen, err := NewNamespaceMetadataGenerator(cfg, nil, client)
+	require.NoError(t, err)
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equal(t, test.output, metagen.Generate(test.input))
@@ -161,7 +162,8 @@ func TestNamespace_GenerateFromName(t *testing.T) {
 		namespaces := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err = namespaces.Add(test.input)
 		require.NoError(t, err)
-		metagen := NewNamespaceMetadataGenerator(cfg, namespaces, client)
+		metagen, err := NewNamespaceMetadataGenerator(cfg, namespaces, client)
+		require.NoError(t, err)
 
 		accessor, err := meta.Accessor(test.input)
 		require.NoError(t, err)
@@ -27,17 +27,31 @@ import (
 	"github.com/elastic/elastic-agent-libs/mapstr"
 )
 
+// nodeConditions are the node conditions added as node.conditions.* when extended fields are enabled
+var nodeConditions = map[v1.NodeConditionType]string{
+	v1.NodeReady:              "ready",
+	v1.NodeMemoryPressure:     "memory_pressure",
+	v1.NodeDiskPressure:       "disk_pressure",
+	v1.NodePIDPressure:        "pid_pressure",
+	v1.NodeNetworkUnavailable: "network_unavailable",
+}
+
 type node struct {
 	store    cache.Store
 	resource *Resource
 }
 
 // NewNodeMetadataGenerator creates a metagen for service resources
-func NewNodeMetadataGenerator(cfg *config.C, nodes cache.Store, client k8s.Interface) MetaGen {
+func NewNodeMetadataGenerator(cfg *config.C, nodes cache.Store, client k8s.Interface) (MetaGen, error) {
+	resource, err := NewResourceMetadataGenerator(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
 	return &node{
-		resource: NewResourceMetadataGenerator(cfg, client),
+		resource: resource,
 		store:    nodes,
-	}
+	}, nil
 }
 
 // Generate generates node metadata from a resource object
@@ -61,22 +75,57 @@ func (n *node) Generate(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M
 
 // GenerateECS generates node ECS metadata from a resource object
 func (n *node) GenerateECS(obj kubernetes.Resource) mapstr.M {
-	return n.resource.GenerateECS(obj)
+	ecsMeta := n.resource.GenerateECS(obj)
+	if !n.resource.config.ExtendedFields {
+		return ecsMeta
+	}
+
+	node, ok := obj.(*kubernetes.Node)
+	if !ok {
+		return ecsMeta
+	}
+	ecsMeta.DeepUpdate(nodeECSFields(node))
+	return ecsMeta
+}
+
+// generateECSFromName generates the cloud and host ECS fields of a node from its name, they are added to
+// the metadata of the pods of the node when extended fields are enabled for nodes
+func (n *node) generateECSFromName(name string) mapstr.M {
+	if n.store == nil || !n.resource.config.ExtendedFields {
+		return nil
+	}
+	obj, ok, _ := n.store.GetByKey(name)
+	if !ok {
+		return nil
+	}
+	node, ok := obj.(*kubernetes.Node)
+	if !ok {
+		return nil
+	}
+	return nodeECSFields(node)
 }
 
 // GenerateK8s generates node metadata from a resource object
 func (n *node) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	return n.resource.finishK8s(n.generateK8s(obj), opts...)
+}
+
+// generateK8s generates node metadata from a resource object, without applying the field policy and the options
+func (n *node) generateK8s(obj kubernetes.Resource) mapstr.M {
 	node, ok := obj.(*kubernetes.Node)
 	if !ok {
 		return nil
 	}
 
-	meta := n.resource.GenerateK8s("node", obj, opts...)
+	meta := n.resource.generateK8s("node", obj)
 	// Add extra fields in here if need be
 	hostname := getHostName(node)
 	if hostname != "" {
 		_, _ = meta.Put("node.hostname", hostname)
 	}
+	if n.resource.config.ExtendedFields {
+		addNodeExtendedFields(meta, node)
+	}
 	return meta
 }
 
@@ -98,6 +147,16 @@ func (n *node) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
 	return nil
 }
 
+// generateNestedFromName generates node metadata from a node name to embed it in the metadata of other
+// resources, without applying the field policy
+func (n *node) generateNestedFromName(name string, opts ...FieldOptions) mapstr.M {
+	obj, ok := getFromStore(n.store, name)
+	if !ok {
+		return nil
+	}
+	return applyFieldOptions(n.generateK8s(obj), opts)
+}
+
 // getHostName returns the HostName address of the node
 func getHostName(node *v1.Node) string {
 	for _, adr := range node.Status.Addresses {
@@ -107,3 +166,102 @@ func getHostName(node *v1.Node) string {
 	}
 	return ""
 }
+
+// addNodeExtendedFields adds the topology, capacity, taints and conditions fields of the node
+func addNodeExtendedFields(out mapstr.M, node *kubernetes.Node) {
+	if region := nodeLabel(node, v1.LabelTopologyRegion, v1.LabelFailureDomainBetaRegion); region != "" {
+		_, _ = out.Put("node.topology.region", region)
+	}
+	if zone := nodeLabel(node, v1.LabelTopologyZone, v1.LabelFailureDomainBetaZone); zone != "" {
+		_, _ = out.Put("node.topology.zone", zone)
+	}
+	if instanceType := nodeLabel(node, v1.LabelInstanceTypeStable, v1.LabelInstanceType); instanceType != "" {
+		_, _ = out.Put("node.instance_type", instanceType)
+	}
+
+	info := node.Status.NodeInfo
+	if info.KubeletVersion != "" {
+		_, _ = out.Put("node.kubelet.version", info.KubeletVersion)
+	}
+	if info.ContainerRuntimeVersion != "" {
+		_, _ = out.Put("node.container_runtime.version", info.ContainerRuntimeVersion)
+	}
+	if info.OperatingSystem != "" {
+		_, _ = out.Put("node.os", info.OperatingSystem)
+	}
+	if info.Architecture != "" {
+		_, _ = out.Put("node.architecture", info.Architecture)
+	}
+
+	if cpu, ok := node.Status.Allocatable[v1.ResourceCPU]; ok {
+		_, _ = out.Put("node.allocatable.cpu", cpu.String())
+	}
+	if memory, ok := node.Status.Allocatable[v1.ResourceMemory]; ok {
+		_, _ = out.Put("node.allocatable.memory", memory.String())
+	}
+
+	if len(node.Spec.Taints) != 0 {
+		taints := make([]mapstr.M, 0, len(node.Spec.Taints))
+		for _, taint := range node.Spec.Taints {
+			t := mapstr.M{
+				"key":    taint.Key,
+				"effect": string(taint.Effect),
+			}
+			if taint.Value != "" {
+				t["value"] = taint.Value
+			}
+			taints = append(taints, t)
+		}
+		_, _ = out.Put("node.taints", taints)
+	}
+
+	conditions := mapstr.M{}
+	for _, condition := range node.Status.Conditions {
+		if field, ok := nodeConditions[condition.Type]; ok {
+			conditions[field] = condition.Status == v1.ConditionTrue
+		}
+	}
+	if len(conditions) != 0 {
+		_, _ = out.Put("node.conditions", conditions)
+	}
+}
+
+// nodeECSFields returns the cloud and host ECS fields of a node
+func nodeECSFields(node *kubernetes.Node) mapstr.M {
+	ecsMeta := mapstr.M{}
+	if region := nodeLabel(node, v1.LabelTopologyRegion, v1.LabelFailureDomainBetaRegion); region != "" {
+		_, _ = ecsMeta.Put("cloud.region", region)
+	}
+	if zone := nodeLabel(node, v1.LabelTopologyZone, v1.LabelFailureDomainBetaZone); zone != "" {
+		_, _ = ecsMeta.Put("cloud.availability_zone", zone)
+	}
+	if instanceType := nodeLabel(node, v1.LabelInstanceTypeStable, v1.LabelInstanceType); instanceType != "" {
+		_, _ = ecsMeta.Put("cloud.machine.type", instanceType)
+	}
+
+	info := node.Status.NodeInfo
+	if info.Architecture != "" {
+		_, _ = ecsMeta.Put("host.architecture", info.Architecture)
+	}
+	if info.OperatingSystem != "" {
+		_, _ = ecsMeta.Put("host.os.type", info.OperatingSystem)
+	}
+	if info.OSImage != "" {
+		_, _ = ecsMeta.Put("host.os.full", info.OSImage)
+	}
+	if info.KernelVersion != "" {
+		_, _ = ecsMeta.Put("host.os.kernel", info.KernelVersion)
+	}
+	return ecsMeta
+}
+
+// nodeLabel returns the value of the first of the given labels set in the node,
+// so deprecated well-known labels can be used as a fallback of the current ones
+func nodeLabel(node *kubernetes.Node, labels ...string) string {
+	for _, label := range labels {
+		if value := node.Labels[label]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
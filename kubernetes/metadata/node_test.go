@@ -25,6 +25,7 @@ import (
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
@@ -83,7 +84,8 @@ func TestNode_Generate(t *testing.T) {
 	cfg, _ := config.NewConfigFrom(Config{
 		IncludeAnnotations: []string{"key2"},
 	})
-	metagen := NewNodeMetadataGenerator(cfg, nil, client)
+	metagen, err := NewNodeMetadataGenerator(cfg, nil, client)
+	require.NoError(t, err)
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equal(t, test.output, metagen.Generate(test.input))
@@ -143,7 +145,8 @@ func TestNode_GenerateFromName(t *testing.T) {
 		nodes := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err = nodes.Add(test.input)
 		require.NoError(t, err)
-		metagen := NewNodeMetadataGenerator(cfg, nodes, client)
+		metagen, err := NewNodeMetadataGenerator(cfg, nodes, client)
+		require.NoError(t, err)
 
 		accessor, err := meta.Accessor(test.input)
 		require.NoError(t, err)
@@ -153,3 +156,144 @@ func TestNode_GenerateFromName(t *testing.T) {
 		})
 	}
 }
+
+func TestNode_GenerateWithExtendedFields(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			UID:  types.UID(uid),
+			Labels: map[string]string{
+				"topology.kubernetes.io/region":          "us-east-1",
+				"failure-domain.beta.kubernetes.io/zone": "us-east-1a",
+				"node.kubernetes.io/instance-type":       "m5.large",
+			},
+		},
+		Spec: v1.NodeSpec{
+			Taints: []v1.Taint{
+				{Key: "dedicated", Value: "infra", Effect: v1.TaintEffectNoSchedule},
+				{Key: "node.kubernetes.io/unreachable", Effect: v1.TaintEffectNoExecute},
+			},
+		},
+		Status: v1.NodeStatus{
+			Addresses: []v1.NodeAddress{{Type: v1.NodeHostName, Address: "node1"}},
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    k8sresource.MustParse("1930m"),
+				v1.ResourceMemory: k8sresource.MustParse("7Gi"),
+			},
+			NodeInfo: v1.NodeSystemInfo{
+				KubeletVersion:          "v1.29.5",
+				ContainerRuntimeVersion: "containerd://1.7.13",
+				OperatingSystem:         "linux",
+				Architecture:            "amd64",
+				OSImage:                 "Ubuntu 22.04.4 LTS",
+				KernelVersion:           "6.5.0-1020-aws",
+			},
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: v1.ConditionTrue},
+				{Type: v1.NodeMemoryPressure, Status: v1.ConditionFalse},
+				{Type: v1.NodeDiskPressure, Status: v1.ConditionTrue},
+			},
+		},
+	}
+
+	cfg, err := config.NewConfigFrom(map[string]any{
+		"extended_fields": true,
+		"include_labels":  []string{"none"},
+	})
+	require.NoError(t, err)
+	metagen, err := NewNodeMetadataGenerator(cfg, nil, client)
+	require.NoError(t, err)
+
+	assert.Equal(t, mapstr.M{
+		"kubernetes": mapstr.M{
+			"node": mapstr.M{
+				"name":          name,
+				"uid":           uid,
+				"hostname":      "node1",
+				"instance_type": "m5.large",
+				"topology": mapstr.M{
+					"region": "us-east-1",
+					"zone":   "us-east-1a",
+				},
+				"kubelet": mapstr.M{
+					"version": "v1.29.5",
+				},
+				"container_runtime": mapstr.M{
+					"version": "containerd://1.7.13",
+				},
+				"os":           "linux",
+				"architecture": "amd64",
+				"allocatable": mapstr.M{
+					"cpu":    "1930m",
+					"memory": "7Gi",
+				},
+				"taints": []mapstr.M{
+					{"key": "dedicated", "value": "infra", "effect": "NoSchedule"},
+					{"key": "node.kubernetes.io/unreachable", "effect": "NoExecute"},
+				},
+				"conditions": mapstr.M{
+					"ready":           true,
+					"memory_pressure": false,
+					"disk_pressure":   true,
+				},
+			},
+		},
+		"cloud": mapstr.M{
+			"region":            "us-east-1",
+			"availability_zone": "us-east-1a",
+			"machine": mapstr.M{
+				"type": "m5.large",
+			},
+		},
+		"host": mapstr.M{
+			"architecture": "amd64",
+			"os": mapstr.M{
+				"type":   "linux",
+				"full":   "Ubuntu 22.04.4 LTS",
+				"kernel": "6.5.0-1020-aws",
+			},
+		},
+	}, metagen.Generate(node))
+
+	// extended fields are not added by default
+	metagen, err = NewNodeMetadataGenerator(config.NewConfig(), nil, client)
+	require.NoError(t, err)
+	meta := metagen.Generate(node)
+	assert.NotContains(t, meta, "cloud")
+	assert.NotContains(t, meta, "host")
+	_, err = meta.GetValue("kubernetes.node.taints")
+	assert.Error(t, err)
+
+	// the cloud and host fields of the node are added to the metadata of its pods
+	nodes := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, nodes.Add(node))
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: defaultNs,
+		},
+		Spec: v1.PodSpec{
+			NodeName: name,
+		},
+	}
+	nodeMetagen, err := NewNodeMetadataGenerator(cfg, nodes, client)
+	require.NoError(t, err)
+	podMetagen, err := NewPodMetadataGenerator(config.NewConfig(), nil, client, nodeMetagen, nil, nil, nil, &AddResourceMetadataConfig{})
+	require.NoError(t, err)
+	meta = podMetagen.Generate(pod)
+	region, err := meta.GetValue("cloud.region")
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", region)
+	kernel, err := meta.GetValue("host.os.kernel")
+	require.NoError(t, err)
+	assert.Equal(t, "6.5.0-1020-aws", kernel)
+
+	nodeMetagen, err = NewNodeMetadataGenerator(config.NewConfig(), nodes, client)
+	require.NoError(t, err)
+	podMetagen, err = NewPodMetadataGenerator(config.NewConfig(), nil, client, nodeMetagen, nil, nil, nil, &AddResourceMetadataConfig{})
+	require.NoError(t, err)
+	meta = podMetagen.Generate(pod)
+	assert.NotContains(t, meta, "cloud")
+	assert.NotContains(t, meta, "host")
+}
@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// DefaultOwnerDepth is the maximum number of owners followed when no depth is given to NewOwnerWalker
+const DefaultOwnerDepth = 5
+
+// OwnerWalker follows the controller owner references of an object through the given stores to find
+// the top level workload of the object, like the Deployment of a Pod or the Rollout of an Argo Rollouts Pod.
+type OwnerWalker struct {
+	stores   map[string]cache.Store
+	maxDepth int
+}
+
+// NewOwnerWalker creates an OwnerWalker. stores maps kinds, like ReplicaSet or CloneSet, to the store
+// of a watcher of that kind. Stores of metadata watchers can be used as only the owner references are needed.
+// maxDepth is the maximum number of owners followed, DefaultOwnerDepth is used if it is not positive.
+func NewOwnerWalker(stores map[string]cache.Store, maxDepth int) *OwnerWalker {
+	if maxDepth <= 0 {
+		maxDepth = DefaultOwnerDepth
+	}
+	return &OwnerWalker{
+		stores:   stores,
+		maxDepth: maxDepth,
+	}
+}
+
+// Walk returns the chain of controllers of the given object, starting from its direct controller.
+// The walk stops when an owner has no controller, when the kind of an owner has no store or it is
+// not found in it, or when maxDepth owners are found.
+func (w *OwnerWalker) Walk(obj metav1.Object) []metav1.OwnerReference {
+	var chain []metav1.OwnerReference
+	namespace := obj.GetNamespace()
+	current := obj
+	for len(chain) < w.maxDepth {
+		ref := metav1.GetControllerOfNoCopy(current)
+		if ref == nil {
+			break
+		}
+		chain = append(chain, *ref)
+
+		owner := w.get(ref, namespace)
+		if owner == nil {
+			break
+		}
+		current = owner
+	}
+	return chain
+}
+
+// Generate generates the metadata of the owner chain of the given object.
+// Metadata map is in the following form:
+//
+//	{
+//		"replicaset": {"name": "nginx-5d8f7c6b4"},
+//		"deployment": {"name": "nginx"},
+//		"workload": {
+//			"kind": "Deployment",
+//			"name": "nginx",
+//			"owners": [{"kind": "ReplicaSet", "name": "nginx-5d8f7c6b4"}, {"kind": "Deployment", "name": "nginx"}],
+//		},
+//	}
+func (w *OwnerWalker) Generate(obj metav1.Object) mapstr.M {
+	chain := w.Walk(obj)
+	if len(chain) == 0 {
+		return nil
+	}
+
+	out := mapstr.M{}
+	owners := make([]mapstr.M, 0, len(chain))
+	for _, ref := range chain {
+		owners = append(owners, mapstr.M{
+			"kind": ref.Kind,
+			"name": ref.Name,
+		})
+		_, _ = out.Put(strings.ToLower(ref.Kind)+".name", ref.Name)
+	}
+
+	top := chain[len(chain)-1]
+	_, _ = out.Put("workload", mapstr.M{
+		"kind":   top.Kind,
+		"name":   top.Name,
+		"owners": owners,
+	})
+	return out
+}
+
+// get returns the owner referenced by ref from the store of its kind
+func (w *OwnerWalker) get(ref *metav1.OwnerReference, namespace string) metav1.Object {
+	store, ok := w.stores[ref.Kind]
+	if !ok || store == nil {
+		return nil
+	}
+
+	// Owners are either in the namespace of the object or cluster scoped
+	obj, exists, _ := store.GetByKey(namespace + "/" + ref.Name)
+	if !exists && namespace != "" {
+		obj, exists, _ = store.GetByKey(ref.Name)
+	}
+	if !exists {
+		return nil
+	}
+
+	owner, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	// Skip owners replaced by a new object with the same name
+	if ref.UID != "" && owner.GetUID() != "" && owner.GetUID() != ref.UID {
+		return nil
+	}
+	return owner
+}
@@ -0,0 +1,181 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func controllerRef(kind, name, uid string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{
+		{
+			APIVersion: "apps/v1",
+			Kind:       kind,
+			Name:       name,
+			UID:        types.UID(uid),
+			Controller: &controller,
+		},
+	}
+}
+
+// newOwnerStores returns metadata-only stores for the chain Pod->ReplicaSet->Rollout->Application
+func newOwnerStores(t *testing.T) map[string]cache.Store {
+	replicasets := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, replicasets.Add(&metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "nginx-rs",
+			Namespace:       defaultNs,
+			UID:             "rs-uid",
+			OwnerReferences: controllerRef("Rollout", "nginx", "rollout-uid"),
+		},
+	}))
+	rollouts := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, rollouts.Add(&metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "nginx",
+			Namespace:       defaultNs,
+			UID:             "rollout-uid",
+			OwnerReferences: controllerRef("Application", "shop", "app-uid"),
+		},
+	}))
+	return map[string]cache.Store{
+		"ReplicaSet": replicasets,
+		"Rollout":    rollouts,
+	}
+}
+
+func TestOwnerWalker_Walk(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       defaultNs,
+			OwnerReferences: controllerRef("ReplicaSet", "nginx-rs", "rs-uid"),
+		},
+	}
+
+	walker := NewOwnerWalker(newOwnerStores(t), 0)
+	chain := walker.Walk(pod)
+	require.Len(t, chain, 3)
+	assert.Equal(t, "ReplicaSet", chain[0].Kind)
+	assert.Equal(t, "Rollout", chain[1].Kind)
+	// Application is not cached, but it is the controller of the rollout
+	assert.Equal(t, "Application", chain[2].Kind)
+	assert.Equal(t, "shop", chain[2].Name)
+
+	// depth limits the number of owners
+	walker = NewOwnerWalker(newOwnerStores(t), 2)
+	chain = walker.Walk(pod)
+	require.Len(t, chain, 2)
+	assert.Equal(t, "Rollout", chain[1].Kind)
+
+	// the walk stops on owners replaced by a new object
+	stalePod := pod.DeepCopy()
+	stalePod.OwnerReferences = controllerRef("ReplicaSet", "nginx-rs", "old-rs-uid")
+	chain = walker.Walk(stalePod)
+	require.Len(t, chain, 1)
+
+	// objects without controller have no owners
+	assert.Empty(t, walker.Walk(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: defaultNs}}))
+}
+
+func TestOwnerWalker_Generate(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       defaultNs,
+			OwnerReferences: controllerRef("ReplicaSet", "nginx-rs", "rs-uid"),
+		},
+	}
+
+	walker := NewOwnerWalker(newOwnerStores(t), 2)
+	assert.Equal(t, mapstr.M{
+		"replicaset": mapstr.M{
+			"name": "nginx-rs",
+		},
+		"rollout": mapstr.M{
+			"name": "nginx",
+		},
+		"workload": mapstr.M{
+			"kind": "Rollout",
+			"name": "nginx",
+			"owners": []mapstr.M{
+				{"kind": "ReplicaSet", "name": "nginx-rs"},
+				{"kind": "Rollout", "name": "nginx"},
+			},
+		},
+	}, walker.Generate(pod))
+
+	assert.Nil(t, walker.Generate(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: defaultNs}}))
+}
+
+func TestPod_GenerateWithOwnerWalker(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			UID:             types.UID(uid),
+			Namespace:       defaultNs,
+			OwnerReferences: controllerRef("ReplicaSet", "nginx-rs", "rs-uid"),
+		},
+		Spec: v1.PodSpec{
+			NodeName: "testnode",
+		},
+	}
+
+	metagen, err := NewPodMetadataGenerator(config.NewConfig(), nil, client, nil, nil, nil, nil, addResourceMetadata,
+		WithOwnerWalker(NewOwnerWalker(newOwnerStores(t), 2)))
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{
+		"kubernetes": mapstr.M{
+			"pod": mapstr.M{
+				"name": name,
+				"uid":  uid,
+			},
+			"namespace": defaultNs,
+			"replicaset": mapstr.M{
+				"name": "nginx-rs",
+			},
+			"rollout": mapstr.M{
+				"name": "nginx",
+			},
+			"workload": mapstr.M{
+				"kind": "Rollout",
+				"name": "nginx",
+				"owners": []mapstr.M{
+					{"kind": "ReplicaSet", "name": "nginx-rs"},
+					{"kind": "Rollout", "name": "nginx"},
+				},
+			},
+			"node": mapstr.M{
+				"name": "testnode",
+			},
+		},
+	}, metagen.Generate(pod))
+}
@@ -18,6 +18,10 @@
 package metadata
 
 import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
@@ -34,6 +38,69 @@ type pod struct {
 	job                 MetaGen
 	resource            *Resource
 	addResourceMetadata *AddResourceMetadataConfig
+	workloads           map[string]MetaGen
+	workloadWatchers    map[string]kubernetes.Watcher
+	workloadMetadata    bool
+	owners              *OwnerWalker
+	services            cache.Store
+}
+
+// workloadKinds are the kinds of the workloads whose labels and annotations can be added to pods
+var workloadKinds = []string{deploymentType, "StatefulSet", "DaemonSet", "CronJob"}
+
+// workloadMetaGenConstructors build the metagens of the workload kinds from the stores of their watchers
+var workloadMetaGenConstructors = map[string]func(*config.C, cache.Store, k8s.Interface) (MetaGen, error){
+	deploymentType: NewDeploymentMetadataGenerator,
+	"StatefulSet":  NewStatefulSetMetadataGenerator,
+	"DaemonSet":    NewDaemonSetMetadataGenerator,
+	"CronJob":      NewCronJobMetadataGenerator,
+}
+
+// podConditions are the pod conditions added as pod.conditions.* when extended fields are enabled
+var podConditions = map[v1.PodConditionType]string{
+	v1.PodScheduled:              "scheduled",
+	v1.PodInitialized:            "initialized",
+	v1.ContainersReady:           "containers_ready",
+	v1.PodReady:                  "ready",
+	v1.DisruptionTarget:          "disruption_target",
+	v1.PodReadyToStartContainers: "ready_to_start_containers",
+}
+
+// PodMetaGenOption allows configuring optional metagens of a pod metagen
+type PodMetaGenOption func(*pod)
+
+// WithWorkloadMetaGen sets the metagen of a workload kind, one of Deployment, StatefulSet, DaemonSet or CronJob.
+// It is used to add the labels and annotations of the workload owning a pod when AddResourceMetadataConfig.Workload
+// is enabled.
+func WithWorkloadMetaGen(kind string, metaGen MetaGen) PodMetaGenOption {
+	return func(p *pod) {
+		p.workloads[kind] = metaGen
+	}
+}
+
+// WithWorkloadWatcher sets the watcher of a workload kind, one of Deployment, StatefulSet, DaemonSet or CronJob.
+// The metagen of the workload kind is built from the store of the watcher with the AddResourceMetadataConfig.Workload
+// config, unless it is set with WithWorkloadMetaGen.
+func WithWorkloadWatcher(kind string, watcher kubernetes.Watcher) PodMetaGenOption {
+	return func(p *pod) {
+		p.workloadWatchers[kind] = watcher
+	}
+}
+
+// WithOwnerWalker sets the OwnerWalker used to add the owner chain and the top level workload of pods,
+// see OwnerWalker.Generate for the added fields.
+func WithOwnerWalker(owners *OwnerWalker) PodMetaGenOption {
+	return func(p *pod) {
+		p.owners = owners
+	}
+}
+
+// WithServices sets the store of a service watcher, used to add the names of the services selecting a pod
+// as service.name. Adding kubernetes.ServiceSelectorIndexers to the watcher avoids checking every service.
+func WithServices(services cache.Store) PodMetaGenOption {
+	return func(p *pod) {
+		p.services = services
+	}
 }
 
 // NewPodMetadataGenerator creates a metagen for pod resources
@@ -45,17 +112,43 @@ func NewPodMetadataGenerator(
 	namespace MetaGen,
 	replicaset MetaGen,
 	job MetaGen,
-	addResourceMetadata *AddResourceMetadataConfig) MetaGen {
+	addResourceMetadata *AddResourceMetadataConfig,
+	opts ...PodMetaGenOption) (MetaGen, error) {
+
+	resource, err := NewNamespaceAwareResourceMetadataGenerator(cfg, client, namespace)
+	if err != nil {
+		return nil, err
+	}
 
-	return &pod{
-		resource:            NewNamespaceAwareResourceMetadataGenerator(cfg, client, namespace),
+	p := &pod{
+		resource:            resource,
 		store:               pods,
 		node:                node,
 		replicaset:          replicaset,
 		job:                 job,
 		client:              client,
 		addResourceMetadata: addResourceMetadata,
+		workloads:           map[string]MetaGen{},
+		workloadWatchers:    map[string]kubernetes.Watcher{},
+		workloadMetadata:    addResourceMetadata.Workload.Enabled(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.workloadMetadata {
+		for kind, watcher := range p.workloadWatchers {
+			newMetaGen, ok := workloadMetaGenConstructors[kind]
+			if !ok || watcher == nil || p.workloads[kind] != nil {
+				continue
+			}
+			workloadMetaGen, err := newMetaGen(addResourceMetadata.Workload, watcher.Store(), watcher.Client())
+			if err != nil {
+				return nil, fmt.Errorf("failed to create %s metagen: %w", strings.ToLower(kind), err)
+			}
+			p.workloads[kind] = workloadMetaGen
+		}
 	}
+	return p, nil
 }
 
 // Generate generates pod metadata from a resource object
@@ -78,27 +171,55 @@ func (p *pod) Generate(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
 	return meta
 }
 
-// GenerateECS generates pod ECS metadata from a resource object
+// nodeECSGenerator generates the ECS fields of a node from its name
+type nodeECSGenerator interface {
+	generateECSFromName(name string) mapstr.M
+}
+
+// GenerateECS generates pod ECS metadata from a resource object, including the cloud and
+// host ECS fields of its node when they are generated by the node metagen
 func (p *pod) GenerateECS(obj kubernetes.Resource) mapstr.M {
-	return p.resource.GenerateECS(obj)
+	ecsMeta := p.resource.GenerateECS(obj)
+	po, ok := obj.(*kubernetes.Pod)
+	if !ok || po.Spec.NodeName == "" {
+		return ecsMeta
+	}
+	if nodeGen, ok := p.node.(nodeECSGenerator); ok {
+		if nodeMeta := nodeGen.generateECSFromName(po.Spec.NodeName); nodeMeta != nil {
+			ecsMeta.DeepUpdate(nodeMeta)
+		}
+	}
+	return ecsMeta
 }
 
 // GenerateK8s generates pod metadata from a resource object
 func (p *pod) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	return p.resource.finishK8s(p.generateK8s(obj), opts...)
+}
+
+// generateK8s generates pod metadata from a resource object, without applying the field policy and the options
+func (p *pod) generateK8s(obj kubernetes.Resource) mapstr.M {
 	po, ok := obj.(*kubernetes.Pod)
 	if !ok {
 		return nil
 	}
 
-	out := p.resource.GenerateK8s("pod", obj, opts...)
+	out := p.resource.generateK8s("pod", obj)
+
+	// walk the whole owner chain of the Pod, not only the known two hops below.
+	if p.owners != nil {
+		if ownersMeta := p.owners.Generate(po); ownersMeta != nil {
+			out.DeepUpdateNoOverwrite(ownersMeta)
+		}
+	}
 
 	// check if Pod is handled by a ReplicaSet which is controlled by a Deployment.
 	// The hierarchy there is Deployment->ReplicaSet->Pod.
-	if p.addResourceMetadata.Deployment {
+	if p.addResourceMetadata.Deployment || p.workloadMetadata {
 		if p.replicaset != nil {
 			rsName, _ := out.GetValue("replicaset.name")
 			if rsName, ok := rsName.(string); ok {
-				meta := p.replicaset.GenerateFromName(po.Namespace + "/" + rsName)
+				meta := generateNestedFromName(p.replicaset, po.Namespace+"/"+rsName)
 				deploymentName, _ := meta.GetValue("deployment.name")
 				if deploymentName != "" {
 					_, _ = out.Put("deployment.name", deploymentName)
@@ -109,11 +230,11 @@ func (p *pod) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.
 
 	// check if Pod is handled by a Job which is controlled by a CronJob.
 	// The hierarchy there is CronJob->Job->Pod
-	if p.addResourceMetadata.CronJob {
+	if p.addResourceMetadata.CronJob || p.workloadMetadata {
 		if p.job != nil {
 			jobName, _ := out.GetValue("job.name")
 			if jobName, ok := jobName.(string); ok {
-				meta := p.job.GenerateFromName(po.Namespace + "/" + jobName)
+				meta := generateNestedFromName(p.job, po.Namespace+"/"+jobName)
 				cronjobName, _ := meta.GetValue("cronjob.name")
 				if cronjobName != "" {
 					_, _ = out.Put("cronjob.name", cronjobName)
@@ -122,8 +243,14 @@ func (p *pod) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.
 		}
 	}
 
+	if p.workloadMetadata {
+		for _, kind := range workloadKinds {
+			p.addWorkloadMetadata(out, po.Namespace, kind)
+		}
+	}
+
 	if p.node != nil {
-		meta := p.node.GenerateFromName(po.Spec.NodeName, WithMetadata("node"))
+		meta := generateNestedFromName(p.node, po.Spec.NodeName, WithMetadata("node"))
 		if meta != nil {
 			_, _ = out.Put("node", meta["node"])
 		} else {
@@ -137,6 +264,21 @@ func (p *pod) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.
 		_, _ = out.Put("pod.ip", po.Status.PodIP)
 	}
 
+	if p.services != nil {
+		services := kubernetes.ServicesSelectingPod(p.services, po)
+		if len(services) != 0 {
+			names := make([]string, 0, len(services))
+			for _, svc := range services {
+				names = append(names, svc.Name)
+			}
+			_, _ = out.Put("service.name", names)
+		}
+	}
+
+	if p.resource.config.ExtendedFields {
+		addPodExtendedFields(out, po)
+	}
+
 	return out
 }
 
@@ -157,3 +299,88 @@ func (p *pod) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
 
 	return nil
 }
+
+// generateNestedFromName generates pod metadata from a pod name to embed it in the metadata of other
+// resources, without applying the field policy
+func (p *pod) generateNestedFromName(name string, opts ...FieldOptions) mapstr.M {
+	obj, ok := getFromStore(p.store, name)
+	if !ok {
+		return nil
+	}
+	return applyFieldOptions(p.generateK8s(obj), opts)
+}
+
+// addWorkloadMetadata adds the labels and annotations of the workload of the given kind owning the pod
+func (p *pod) addWorkloadMetadata(out mapstr.M, namespace, kind string) {
+	metaGen := p.workloads[kind]
+	if metaGen == nil {
+		return
+	}
+
+	key := strings.ToLower(kind)
+	rawName, _ := out.GetValue(key + ".name")
+	name, ok := rawName.(string)
+	if !ok || name == "" {
+		return
+	}
+
+	meta := generateNestedFromName(metaGen, namespace+"/"+name, WithMetadata(key))
+	if meta == nil {
+		return
+	}
+	for _, field := range []string{"labels", "annotations"} {
+		if value, err := meta.GetValue(key + "." + field); err == nil {
+			_, _ = out.Put(key+"."+field, value)
+		}
+	}
+}
+
+// addPodExtendedFields adds the status and scheduling fields of the pod
+func addPodExtendedFields(out mapstr.M, po *kubernetes.Pod) {
+	if po.Status.Phase != "" {
+		_, _ = out.Put("pod.phase", string(po.Status.Phase))
+	}
+	if po.Status.QOSClass != "" {
+		_, _ = out.Put("pod.qos_class", string(po.Status.QOSClass))
+	}
+	if po.Spec.PriorityClassName != "" {
+		_, _ = out.Put("pod.priority_class", po.Spec.PriorityClassName)
+	}
+	if po.Spec.ServiceAccountName != "" {
+		_, _ = out.Put("pod.service_account", po.Spec.ServiceAccountName)
+	}
+	_, _ = out.Put("pod.host_network", po.Spec.HostNetwork)
+
+	// PodIPs holds both the IPv4 and IPv6 addresses of dual-stack pods
+	if len(po.Status.PodIPs) != 0 {
+		ips := make([]string, 0, len(po.Status.PodIPs))
+		for _, ip := range po.Status.PodIPs {
+			ips = append(ips, ip.IP)
+		}
+		_, _ = out.Put("pod.ips", ips)
+	}
+
+	if po.Status.StartTime != nil {
+		_, _ = out.Put("pod.start_time", po.Status.StartTime.UTC())
+	}
+
+	conditions := mapstr.M{}
+	for _, condition := range po.Status.Conditions {
+		if field, ok := podConditions[condition.Type]; ok {
+			conditions[field] = condition.Status == v1.ConditionTrue
+		}
+	}
+	if len(conditions) != 0 {
+		_, _ = out.Put("pod.conditions", conditions)
+	}
+
+	var claims []string
+	for _, volume := range po.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			claims = append(claims, volume.PersistentVolumeClaim.ClaimName)
+		}
+	}
+	if len(claims) != 0 {
+		_, _ = out.Put("pod.persistentvolumeclaim.names", claims)
+	}
+}
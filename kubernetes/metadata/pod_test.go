@@ -21,6 +21,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -29,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	k8s "k8s.io/client-go/kubernetes"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
 
@@ -435,8 +437,10 @@ func TestPod_Generate(t *testing.T) {
 	replicaSets := cache.NewStore(cache.MetaNamespaceKeyFunc)
 	err = replicaSets.Add(rs)
 	require.NoError(t, err)
-	rsMeta := NewReplicasetMetadataGenerator(config, replicaSets, client)
-	metagen := NewPodMetadataGenerator(config, nil, client, nil, nil, rsMeta, nil, addResourceMetadata)
+	rsMeta, err := NewReplicasetMetadataGenerator(config, replicaSets, client)
+	require.NoError(t, err)
+	metagen, err := NewPodMetadataGenerator(config, nil, client, nil, nil, rsMeta, nil, addResourceMetadata)
+	require.NoError(t, err)
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equal(t, test.output, metagen.Generate(test.input))
@@ -556,7 +560,8 @@ func TestPod_GenerateFromName(t *testing.T) {
 		pods := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err = pods.Add(test.input)
 		require.NoError(t, err)
-		metagen := NewPodMetadataGenerator(config, pods, client, nil, nil, nil, nil, addResourceMetadata)
+		metagen, err := NewPodMetadataGenerator(config, pods, client, nil, nil, nil, nil, addResourceMetadata)
+		require.NoError(t, err)
 
 		accessor, err := meta.Accessor(test.input)
 		require.NoError(t, err)
@@ -671,14 +676,17 @@ func TestPod_GenerateWithNodeNamespace(t *testing.T) {
 		nodes := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err = nodes.Add(test.node)
 		require.NoError(t, err)
-		nodeMeta := NewNodeMetadataGenerator(config, nodes, client)
+		nodeMeta, err := NewNodeMetadataGenerator(config, nodes, client)
+		require.NoError(t, err)
 
 		namespaces := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err = namespaces.Add(test.namespace)
 		require.NoError(t, err)
-		nsMeta := NewNamespaceMetadataGenerator(config, namespaces, client)
+		nsMeta, err := NewNamespaceMetadataGenerator(config, namespaces, client)
+		require.NoError(t, err)
 
-		metagen := NewPodMetadataGenerator(config, pods, client, nodeMeta, nsMeta, nil, nil, addResourceMetadata)
+		metagen, err := NewPodMetadataGenerator(config, pods, client, nodeMeta, nsMeta, nil, nil, addResourceMetadata)
+		require.NoError(t, err)
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equal(t, test.output, metagen.Generate(test.input))
 		})
@@ -832,16 +840,347 @@ func TestPod_GenerateWithNodeNamespaceWithAddResourceConfig(t *testing.T) {
 		nodes := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err = nodes.Add(test.node)
 		require.NoError(t, err)
-		nodeMeta := NewNodeMetadataGenerator(nodeConfig, nodes, client)
+		nodeMeta, err := NewNodeMetadataGenerator(nodeConfig, nodes, client)
+		require.NoError(t, err)
 
 		namespaces := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err = namespaces.Add(test.namespace)
 		require.NoError(t, err)
-		nsMeta := NewNamespaceMetadataGenerator(namespaceConfig, namespaces, client)
+		nsMeta, err := NewNamespaceMetadataGenerator(namespaceConfig, namespaces, client)
+		require.NoError(t, err)
 
-		metagen := NewPodMetadataGenerator(c, pods, client, nodeMeta, nsMeta, nil, nil, &metaConfig)
+		metagen, err := NewPodMetadataGenerator(c, pods, client, nodeMeta, nsMeta, nil, nil, &metaConfig)
+		require.NoError(t, err)
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equal(t, test.output, metagen.Generate(test.input))
 		})
 	}
 }
+
+func TestPod_GenerateWithWorkloadMetadata(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	boolean := true
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "apps",
+					Kind:       "ReplicaSet",
+					Name:       "nginx-rs",
+					UID:        "005f3b90-4b9d-12f8-acf0-31020a8409087",
+					Controller: &boolean,
+				},
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName: "testnode",
+		},
+	}
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx-rs",
+			Namespace: defaultNs,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "apps",
+					Kind:       "Deployment",
+					Name:       "nginx-deployment",
+					UID:        "005f3b90-4b9d-12f8-acf0-31020a840144",
+					Controller: &boolean,
+				},
+			},
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx-deployment",
+			Namespace: defaultNs,
+			Labels: map[string]string{
+				"team": "platform",
+			},
+			Annotations: map[string]string{
+				"owner": "sre",
+			},
+		},
+	}
+
+	cfg := config.NewConfig()
+	workloadCfg, err := config.NewConfigFrom(map[string]any{
+		"include_annotations": []string{"owner"},
+	})
+	require.NoError(t, err)
+
+	replicasets := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, replicasets.Add(rs))
+	deployments := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, deployments.Add(deployment))
+	rsMeta, err := NewReplicasetMetadataGenerator(cfg, replicasets, client)
+	require.NoError(t, err)
+	deploymentMeta, err := NewDeploymentMetadataGenerator(workloadCfg, deployments, client)
+	require.NoError(t, err)
+
+	expected := mapstr.M{
+		"kubernetes": mapstr.M{
+			"pod": mapstr.M{
+				"name": name,
+				"uid":  uid,
+			},
+			"namespace": defaultNs,
+			"replicaset": mapstr.M{
+				"name": "nginx-rs",
+			},
+			"deployment": mapstr.M{
+				"name": "nginx-deployment",
+				"labels": mapstr.M{
+					"team": "platform",
+				},
+				"annotations": mapstr.M{
+					"owner": "sre",
+				},
+			},
+			"node": mapstr.M{
+				"name": "testnode",
+			},
+		},
+	}
+
+	metaConfig := &AddResourceMetadataConfig{
+		Deployment: true,
+		Workload:   workloadCfg,
+	}
+	metagen, err := NewPodMetadataGenerator(cfg, nil, client, nil, nil, rsMeta, nil, metaConfig,
+		WithWorkloadMetaGen("Deployment", deploymentMeta))
+	require.NoError(t, err)
+	assert.Equal(t, expected, metagen.Generate(pod))
+
+	// workload metadata is not added when it is not enabled
+	metaConfig = &AddResourceMetadataConfig{
+		Deployment: true,
+	}
+	metagen, err = NewPodMetadataGenerator(cfg, nil, client, nil, nil, rsMeta, nil, metaConfig,
+		WithWorkloadMetaGen("Deployment", deploymentMeta))
+	require.NoError(t, err)
+	_ = expected.Delete("kubernetes.deployment.labels")
+	_ = expected.Delete("kubernetes.deployment.annotations")
+	assert.Equal(t, expected, metagen.Generate(pod))
+}
+
+type workloadWatcherMock struct {
+	kubernetes.Watcher
+	store  cache.Store
+	client k8s.Interface
+}
+
+func (w *workloadWatcherMock) Store() cache.Store {
+	return w.store
+}
+
+func (w *workloadWatcherMock) Client() k8s.Interface {
+	return w.client
+}
+
+func TestGetPodMetaGenWithWorkloadWatchers(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	boolean := true
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "apps",
+					Kind:       "ReplicaSet",
+					Name:       "nginx-rs",
+					UID:        "005f3b90-4b9d-12f8-acf0-31020a8409087",
+					Controller: &boolean,
+				},
+			},
+		},
+	}
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx-rs",
+			Namespace: defaultNs,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "apps",
+					Kind:       "Deployment",
+					Name:       "nginx-deployment",
+					UID:        "005f3b90-4b9d-12f8-acf0-31020a840144",
+					Controller: &boolean,
+				},
+			},
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx-deployment",
+			Namespace: defaultNs,
+			Labels: map[string]string{
+				"team": "platform",
+			},
+		},
+	}
+
+	replicasets := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, replicasets.Add(rs))
+	deployments := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, deployments.Add(deployment))
+
+	workloadCfg, err := config.NewConfigFrom(map[string]any{"enabled": true})
+	require.NoError(t, err)
+	// Deployment is not enabled, workload metadata is enough to add the labels of the deployment
+	metaConfig := &AddResourceMetadataConfig{Workload: workloadCfg}
+	metagen, err := GetPodMetaGen(config.NewConfig(),
+		&workloadWatcherMock{store: cache.NewStore(cache.MetaNamespaceKeyFunc), client: client},
+		nil, nil,
+		&workloadWatcherMock{store: replicasets, client: client},
+		nil,
+		metaConfig,
+		WithWorkloadWatcher("Deployment", &workloadWatcherMock{store: deployments, client: client}))
+	require.NoError(t, err)
+
+	meta := metagen.Generate(pod)
+	deploymentName, err := meta.GetValue("kubernetes.deployment.name")
+	require.NoError(t, err)
+	assert.Equal(t, "nginx-deployment", deploymentName)
+	labels, err := meta.GetValue("kubernetes.deployment.labels")
+	require.NoError(t, err)
+	assert.Equal(t, mapstr.M{"team": "platform"}, labels)
+}
+
+func TestPod_GenerateWithExtendedFields(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	startTime := metav1.NewTime(time.Date(2024, 5, 10, 8, 30, 0, 0, time.UTC))
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+		},
+		Spec: v1.PodSpec{
+			NodeName:           "testnode",
+			PriorityClassName:  "high-priority",
+			ServiceAccountName: "nginx",
+			Volumes: []v1.Volume{
+				{
+					Name: "data",
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "nginx-data"},
+					},
+				},
+				{
+					Name:         "tmp",
+					VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+				},
+			},
+		},
+		Status: v1.PodStatus{
+			Phase:    v1.PodRunning,
+			QOSClass: v1.PodQOSBurstable,
+			PodIP:    "10.0.0.1",
+			PodIPs: []v1.PodIP{
+				{IP: "10.0.0.1"},
+				{IP: "fd00::1"},
+			},
+			StartTime: &startTime,
+			Conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionTrue},
+				{Type: v1.PodReady, Status: v1.ConditionFalse},
+				{Type: v1.PodConditionType("example.com/gate"), Status: v1.ConditionTrue},
+			},
+		},
+	}
+
+	expected := mapstr.M{
+		"pod": mapstr.M{
+			"name": name,
+			"uid":  uid,
+			"ip":   "10.0.0.1",
+		},
+		"namespace": defaultNs,
+		"node": mapstr.M{
+			"name": "testnode",
+		},
+	}
+
+	metagen, err := NewPodMetadataGenerator(config.NewConfig(), nil, client, nil, nil, nil, nil, addResourceMetadata)
+	require.NoError(t, err)
+	assert.Equal(t, expected, metagen.GenerateK8s(pod))
+
+	cfg, err := config.NewConfigFrom(map[string]any{
+		"extended_fields": true,
+	})
+	require.NoError(t, err)
+	metagen, err = NewPodMetadataGenerator(cfg, nil, client, nil, nil, nil, nil, addResourceMetadata)
+	require.NoError(t, err)
+
+	expectedPod := expected["pod"].(mapstr.M)
+	expectedPod.Update(mapstr.M{
+		"phase":           "Running",
+		"qos_class":       "Burstable",
+		"priority_class":  "high-priority",
+		"service_account": "nginx",
+		"host_network":    false,
+		"ips":             []string{"10.0.0.1", "fd00::1"},
+		"start_time":      startTime.UTC(),
+		"persistentvolumeclaim": mapstr.M{
+			"names": []string{"nginx-data"},
+		},
+		"conditions": mapstr.M{
+			"scheduled": true,
+			"ready":     false,
+		},
+	})
+	assert.Equal(t, expected, metagen.GenerateK8s(pod))
+}
+
+func TestPod_GenerateWithServices(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+			Labels: map[string]string{
+				"app": "nginx",
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName: "testnode",
+		},
+	}
+
+	services := cache.NewIndexer(cache.MetaNamespaceKeyFunc, kubernetes.ServiceSelectorIndexers())
+	for _, svcName := range []string{"nginx", "nginx-headless"} {
+		require.NoError(t, services.Add(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcName,
+				Namespace: defaultNs,
+			},
+			Spec: v1.ServiceSpec{
+				Selector: map[string]string{"app": "nginx"},
+			},
+		}))
+	}
+
+	metagen, err := NewPodMetadataGenerator(config.NewConfig(), nil, client, nil, nil, nil, nil, addResourceMetadata,
+		WithServices(services))
+	require.NoError(t, err)
+	meta := metagen.GenerateK8s(pod)
+	names, err := meta.GetValue("service.name")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nginx", "nginx-headless"}, names)
+
+	// pods not selected by any service don't have service metadata
+	pod.Labels["app"] = "api"
+	meta = metagen.GenerateK8s(pod)
+	assert.NotContains(t, meta, "service")
+}
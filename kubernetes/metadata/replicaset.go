@@ -38,11 +38,16 @@ type replicaset struct {
 }
 
 // NewReplicasetMetadataGenerator creates a metagen for replicaset resources
-func NewReplicasetMetadataGenerator(cfg *config.C, replicasets cache.Store, client k8s.Interface) MetaGen {
+func NewReplicasetMetadataGenerator(cfg *config.C, replicasets cache.Store, client k8s.Interface) (MetaGen, error) {
+	resource, err := NewResourceMetadataGenerator(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
 	return &replicaset{
-		resource: NewResourceMetadataGenerator(cfg, client),
+		resource: resource,
 		store:    replicasets,
-	}
+	}, nil
 }
 
 // Generate generates replicaset metadata from a resource object
@@ -98,6 +103,19 @@ func (rs *replicaset) GenerateFromName(name string, opts ...FieldOptions) mapstr
 	return nil
 }
 
+// generateNestedFromName generates replicaset metadata from a replicaset name to embed it in the metadata of other
+// resources, without applying the field policy
+func (rs *replicaset) generateNestedFromName(name string, opts ...FieldOptions) mapstr.M {
+	obj, ok := getFromStore(rs.store, name)
+	if !ok {
+		return nil
+	}
+	if _, ok := obj.(metav1.Object); !ok {
+		return nil
+	}
+	return applyFieldOptions(rs.resource.generateK8s(resourceType, obj), opts)
+}
+
 // RemoveUnnecessaryReplicaSetData removes all data from a ReplicaSet resource, except what we need to compute
 // Pod metadata. This function works for both ReplicaSet and PartialObjectMetadata.
 func RemoveUnnecessaryReplicaSetData(obj any) (any, error) {
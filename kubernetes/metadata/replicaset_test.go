@@ -107,7 +107,8 @@ func TestReplicaset_Generate(t *testing.T) {
 	}
 
 	cfg := config.NewConfig()
-	metagen := NewReplicasetMetadataGenerator(cfg, nil, client)
+	metagen, err := NewReplicasetMetadataGenerator(cfg, nil, client)
+	require.NoError(t, err)
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equal(t, test.output, metagen.Generate(test.input))
@@ -222,7 +223,8 @@ func TestReplicaset_GenerateFromName(t *testing.T) {
 		replicasets := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err := replicasets.Add(test.input)
 		require.NoError(t, err)
-		metagen := NewReplicasetMetadataGenerator(cfg, replicasets, client)
+		metagen, err := NewReplicasetMetadataGenerator(cfg, replicasets, client)
+		require.NoError(t, err)
 
 		accessor, err := meta.Accessor(test.input)
 		require.NoError(t, err)
@@ -18,7 +18,7 @@
 package metadata
 
 import (
-	"regexp"
+	"fmt"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -26,6 +26,7 @@ import (
 	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
 
 	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/elastic/elastic-agent-autodiscover/utils"
 	"github.com/elastic/elastic-agent-libs/config"
@@ -40,31 +41,42 @@ type Resource struct {
 	config      *Config
 	clusterInfo ClusterInfo
 	namespace   MetaGen
+	matchers    *resourceMatchers
 }
 
-// NewResourceMetadataGenerator creates a metadata generator for a generic resource
-func NewResourceMetadataGenerator(cfg *config.C, client k8s.Interface) *Resource {
+// NewResourceMetadataGenerator creates a metadata generator for a generic resource.
+// An error is returned if the config cannot be unpacked or any of its patterns is not valid.
+func NewResourceMetadataGenerator(cfg *config.C, client k8s.Interface) (*Resource, error) {
 	var c Config
 	err := c.Unmarshal(cfg)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to unpack metadata config: %w", err)
+	}
+
+	matchers, err := newResourceMatchers(&c)
+	if err != nil {
+		return nil, err
 	}
 
 	r := &Resource{
-		config: &c,
+		config:   &c,
+		matchers: matchers,
 	}
 	clusterInfo, err := GetKubernetesClusterIdentifier(cfg, client)
 	if err == nil {
 		r.clusterInfo = clusterInfo
 	}
-	return r
+	return r, nil
 }
 
 // NewNamespaceAwareResourceMetadataGenerator creates a metadata generator with informatuon about namespace
-func NewNamespaceAwareResourceMetadataGenerator(cfg *config.C, client k8s.Interface, namespace MetaGen) *Resource {
-	r := NewResourceMetadataGenerator(cfg, client)
+func NewNamespaceAwareResourceMetadataGenerator(cfg *config.C, client k8s.Interface, namespace MetaGen) (*Resource, error) {
+	r, err := NewResourceMetadataGenerator(cfg, client)
+	if err != nil {
+		return nil, err
+	}
 	r.namespace = namespace
-	return r
+	return r, nil
 }
 
 // Generate generates metadata from a resource object
@@ -95,11 +107,35 @@ func (r *Resource) GenerateECS(_ kubernetes.Resource) mapstr.M {
 	if r.clusterInfo.Name != "" {
 		_, _ = ecsMeta.Put("orchestrator.cluster.name", r.clusterInfo.Name)
 	}
+	if r.clusterInfo.ID != "" {
+		_, _ = ecsMeta.Put("orchestrator.cluster.id", r.clusterInfo.ID)
+	}
 	return ecsMeta
 }
 
 // GenerateK8s takes a kind and an object and creates metadata for the same
 func (r *Resource) GenerateK8s(kind string, obj kubernetes.Resource, options ...FieldOptions) mapstr.M {
+	return r.finishK8s(r.generateK8s(kind, obj), options...)
+}
+
+// finishK8s applies the field policy and then the options to the metadata of a resource. Metagens adding
+// fields to the metadata generated by generateK8s call it once all the fields are added, so the policy
+// applies to all of them.
+func (r *Resource) finishK8s(meta mapstr.M, options ...FieldOptions) mapstr.M {
+	if meta == nil {
+		return nil
+	}
+	meta = r.matchers.fieldPolicy.apply(meta)
+
+	for _, option := range options {
+		option(meta)
+	}
+
+	return meta
+}
+
+// generateK8s creates the common metadata of a resource, without applying the field policy and the options
+func (r *Resource) generateK8s(kind string, obj kubernetes.Resource) mapstr.M {
 	accessor, err := meta.Accessor(obj)
 	if err != nil {
 		return nil
@@ -109,20 +145,24 @@ func (r *Resource) GenerateK8s(kind string, obj kubernetes.Resource, options ...
 	if len(r.config.IncludeLabels) == 0 {
 		labelMap = GenerateMap(accessor.GetLabels(), r.config.LabelsDedot)
 	} else {
-		labelMap = generateMapSubset(accessor.GetLabels(), r.config.IncludeLabels, r.config.LabelsDedot, r.config.UseRegexInclude)
-	}
-
-	var labelMaptoExclude mapstr.M
-	if len(r.config.ExcludeLabels) != 0 {
-		labelMaptoExclude = generateMapSubset(accessor.GetLabels(), r.config.ExcludeLabels, r.config.LabelsDedot, r.config.UseRegexExclude)
+		labelMap = r.matchers.includeLabels.subset(accessor.GetLabels(), r.config.LabelsDedot)
 	}
 
 	// Exclude any labels that are present in the exclude_labels config
-	for label := range labelMaptoExclude {
-		_ = labelMap.Delete(label)
+	if r.matchers.excludeLabels != nil {
+		for label := range r.matchers.excludeLabels.subset(accessor.GetLabels(), r.config.LabelsDedot) {
+			_ = labelMap.Delete(label)
+		}
 	}
 
-	annotationsMap := generateMapSubset(accessor.GetAnnotations(), r.config.IncludeAnnotations, r.config.AnnotationsDedot, r.config.UseRegexInclude)
+	annotationsMap := r.matchers.includeAnnotations.subset(accessor.GetAnnotations(), r.config.AnnotationsDedot)
+
+	// Exclude any annotations that are present in the exclude_annotations config
+	if r.matchers.excludeAnnotations != nil {
+		for annotation := range r.matchers.excludeAnnotations.subset(accessor.GetAnnotations(), r.config.AnnotationsDedot) {
+			_ = annotationsMap.Delete(annotation)
+		}
+	}
 
 	meta := mapstr.M{
 		strings.ToLower(kind): mapstr.M{
@@ -136,7 +176,7 @@ func (r *Resource) GenerateK8s(kind string, obj kubernetes.Resource, options ...
 		_ = safemapstr.Put(meta, "namespace", namespaceName)
 
 		if r.namespace != nil {
-			nsMeta := r.namespace.GenerateFromName(namespaceName)
+			nsMeta := generateNestedFromName(r.namespace, namespaceName)
 			if nsMeta != nil {
 				meta.DeepUpdate(nsMeta)
 			}
@@ -167,50 +207,37 @@ func (r *Resource) GenerateK8s(kind string, obj kubernetes.Resource, options ...
 		_ = safemapstr.Put(meta, "annotations", annotationsMap)
 	}
 
-	for _, option := range options {
-		option(meta)
-	}
-
 	return meta
 }
 
-func generateMapSubset(input map[string]string, keys []string, dedot bool, useregex bool) mapstr.M {
-	output := mapstr.M{}
-	if input == nil {
-		return output
+// nestedMetaGen is implemented by the metagens whose metadata is embedded in the metadata of other resources,
+// like the node and namespace of a pod or the object an event is about. The field policy of the outermost metagen
+// applies to all the metadata it generates, so the embedded metadata is generated without policy to avoid
+// applying it twice, as hashing an already hashed field.
+type nestedMetaGen interface {
+	generateNestedFromName(name string, opts ...FieldOptions) mapstr.M
+}
+
+// generateNestedFromName generates the metadata of a resource from its name to embed it in the metadata of
+// another resource, falling back to GenerateFromName for metagens that don't implement nestedMetaGen
+func generateNestedFromName(metaGen MetaGen, name string, opts ...FieldOptions) mapstr.M {
+	if nested, ok := metaGen.(nestedMetaGen); ok {
+		return nested.generateNestedFromName(name, opts...)
 	}
+	return metaGen.GenerateFromName(name, opts...)
+}
 
-	for _, key := range keys {
-		//This is the part where use_regex is enabled
-		if useregex {
-			pattern, err := regexp.Compile(key)
-			if err == nil {
-				for label, value := range input {
-					matched := pattern.MatchString(label)
-					if matched {
-						if dedot {
-							dedotKey := utils.DeDot(label)
-							_, _ = output.Put(dedotKey, value)
-						} else {
-							_ = safemapstr.Put(output, label, value)
-						}
-					}
-
-				}
-			}
-		} else {
-			value, ok := input[key]
-			if ok {
-				if dedot {
-					dedotKey := utils.DeDot(key)
-					_, _ = output.Put(dedotKey, value)
-				} else {
-					_ = safemapstr.Put(output, key, value)
-				}
-			}
-		}
+// getFromStore returns the resource with the given key in store
+func getFromStore(store cache.Store, key string) (kubernetes.Resource, bool) {
+	if store == nil {
+		return nil, false
 	}
-	return output
+	obj, ok, _ := store.GetByKey(key)
+	if !ok {
+		return nil, false
+	}
+	res, ok := obj.(kubernetes.Resource)
+	return res, ok
 }
 
 func GenerateMap(input map[string]string, dedot bool) mapstr.M {
@@ -125,8 +125,11 @@ func TestResource_Generate(t *testing.T) {
 	var cfg Config
 	err := ucfg.New().Unpack(&cfg)
 	require.NoError(t, err)
+	matchers, err := newResourceMatchers(&cfg)
+	require.NoError(t, err)
 	metagen := &Resource{
-		config: &cfg,
+		config:   &cfg,
+		matchers: matchers,
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -254,9 +257,11 @@ func TestNamespaceAwareResource_GenerateWithNamespace(t *testing.T) {
 		namespaces := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err = namespaces.Add(test.namespace)
 		require.NoError(t, err)
-		nsMeta := NewNamespaceMetadataGenerator(nsConfig, namespaces, client)
+		nsMeta, err := NewNamespaceMetadataGenerator(nsConfig, namespaces, client)
+		require.NoError(t, err)
 
-		metagen := NewNamespaceAwareResourceMetadataGenerator(nsConfig, client, nsMeta)
+		metagen, err := NewNamespaceAwareResourceMetadataGenerator(nsConfig, client, nsMeta)
+		require.NoError(t, err)
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equal(t, test.output, metagen.Generate(test.resourceName, test.input))
 		})
@@ -352,15 +357,21 @@ func Test_generateMapSubset(t *testing.T) {
 		},
 	}
 
+	generateMapSubset := func(input map[string]string, keys []string, dedot bool, mode matchMode) mapstr.M {
+		matcher, err := newKeyMatcher(keys, mode)
+		require.NoError(t, err)
+		return matcher.subset(input, dedot)
+	}
+
 	for i := 0; i <= 4; i++ {
-		output := generateMapSubset(Labels, tests[i].key, false, true)
+		output := generateMapSubset(Labels, tests[i].key, false, matchRegex)
 		assert.Equal(t, tests[i].expectedresult, output)
 	}
 
-	output := generateMapSubset(Labelsdedot, tests[5].key, true, true)
+	output := generateMapSubset(Labelsdedot, tests[5].key, true, matchRegex)
 	assert.Equal(t, tests[5].expectedresult, output)
 
-	output = generateMapSubset(Labelsdedot, tests[6].key, true, false)
+	output = generateMapSubset(Labelsdedot, tests[6].key, true, matchExact)
 	assert.Equal(t, tests[6].expectedresult, output)
 
 }
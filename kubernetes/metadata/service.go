@@ -18,6 +18,8 @@
 package metadata
 
 import (
+	"strconv"
+
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
@@ -28,16 +30,38 @@ import (
 )
 
 type service struct {
-	store    cache.Store
-	resource *Resource
+	store          cache.Store
+	resource       *Resource
+	endpointSlices cache.Store
+}
+
+// ServiceMetaGenOption allows configuring optional stores of a service metagen
+type ServiceMetaGenOption func(*service)
+
+// WithEndpointSlices sets the store of an EndpointSlice watcher, used to add the pods backing a service
+// and their readiness as service.endpoints. Adding kubernetes.EndpointSliceServiceIndexers to the watcher
+// avoids checking every EndpointSlice.
+func WithEndpointSlices(endpointSlices cache.Store) ServiceMetaGenOption {
+	return func(s *service) {
+		s.endpointSlices = endpointSlices
+	}
 }
 
 // NewServiceMetadataGenerator creates a metagen for service resources
-func NewServiceMetadataGenerator(cfg *config.C, services cache.Store, namespace MetaGen, client k8s.Interface) MetaGen {
-	return &service{
-		resource: NewNamespaceAwareResourceMetadataGenerator(cfg, client, namespace),
+func NewServiceMetadataGenerator(cfg *config.C, services cache.Store, namespace MetaGen, client k8s.Interface, opts ...ServiceMetaGenOption) (MetaGen, error) {
+	resource, err := NewNamespaceAwareResourceMetadataGenerator(cfg, client, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &service{
+		resource: resource,
 		store:    services,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
 
 // Generate generates service metadata from a resource object
@@ -71,18 +95,25 @@ func (s *service) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) map
 		return nil
 	}
 
-	out := s.resource.GenerateK8s("service", obj, opts...)
+	out := s.resource.generateK8s("service", obj)
 
 	selectors := svc.Spec.Selector
-	if len(selectors) == 0 {
-		return out
+	if len(selectors) != 0 {
+		svcMap := GenerateMap(selectors, s.resource.config.LabelsDedot)
+		if len(svcMap) != 0 {
+			_ = safemapstr.Put(out, "selectors", svcMap)
+		}
 	}
-	svcMap := GenerateMap(selectors, s.resource.config.LabelsDedot)
-	if len(svcMap) != 0 {
-		_ = safemapstr.Put(out, "selectors", svcMap)
+
+	addServiceSpecFields(out, svc)
+
+	if s.endpointSlices != nil {
+		if endpoints := s.endpoints(svc); len(endpoints) != 0 {
+			_, _ = out.Put("service.endpoints", endpoints)
+		}
 	}
 
-	return out
+	return s.resource.finishK8s(out, opts...)
 }
 
 // GenerateFromName generates pod metadata from a service name
@@ -102,3 +133,101 @@ func (s *service) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
 
 	return nil
 }
+
+// addServiceSpecFields adds the type, addresses and ports of the service
+func addServiceSpecFields(out mapstr.M, svc *kubernetes.Service) {
+	if svc.Spec.Type != "" {
+		_, _ = out.Put("service.type", string(svc.Spec.Type))
+	}
+	if svc.Spec.ClusterIP != "" {
+		_, _ = out.Put("service.cluster_ip", svc.Spec.ClusterIP)
+	}
+	// ClusterIPs holds both the IPv4 and IPv6 addresses of dual-stack services
+	if len(svc.Spec.ClusterIPs) != 0 {
+		_, _ = out.Put("service.cluster_ips", svc.Spec.ClusterIPs)
+	}
+	if len(svc.Spec.ExternalIPs) != 0 {
+		_, _ = out.Put("service.external_ips", svc.Spec.ExternalIPs)
+	}
+	if svc.Spec.ExternalName != "" {
+		_, _ = out.Put("service.external_name", svc.Spec.ExternalName)
+	}
+
+	var ingress []string
+	for _, lb := range svc.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			ingress = append(ingress, lb.IP)
+		}
+		if lb.Hostname != "" {
+			ingress = append(ingress, lb.Hostname)
+		}
+	}
+	if len(ingress) != 0 {
+		_, _ = out.Put("service.load_balancer.ingress", ingress)
+	}
+
+	if len(svc.Spec.Ports) == 0 {
+		return
+	}
+	// Ports are keyed by name, so they can be referenced from templates. Only services with
+	// a single port can leave it unnamed, the port number is used as key then.
+	ports := mapstr.M{}
+	for _, port := range svc.Spec.Ports {
+		p := mapstr.M{
+			"port":     port.Port,
+			"protocol": string(port.Protocol),
+		}
+		if port.TargetPort.String() != "0" {
+			p["target_port"] = port.TargetPort.String()
+		}
+		if port.NodePort != 0 {
+			p["node_port"] = port.NodePort
+		}
+		if port.AppProtocol != nil {
+			p["app_protocol"] = *port.AppProtocol
+		}
+		key := port.Name
+		if key == "" {
+			key = strconv.Itoa(int(port.Port))
+		}
+		ports[key] = p
+	}
+	_, _ = out.Put("service.ports", ports)
+}
+
+// endpoints returns the endpoints of the EndpointSlices of the service. Endpoints of the same pod
+// found in several slices, like the IPv4 and IPv6 slices of dual-stack services, are merged.
+func (s *service) endpoints(svc *kubernetes.Service) []mapstr.M {
+	// Slices are sorted by name to keep the order of the endpoints stable between calls
+	slices := kubernetes.EndpointSlicesOfService(s.endpointSlices, svc)
+
+	var endpoints []mapstr.M
+	pods := map[string]mapstr.M{}
+	for _, slice := range slices {
+		for _, endpoint := range slice.Endpoints {
+			// A nil readiness means an unknown state that consumers should interpret as ready
+			ready := endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+
+			var podName string
+			if endpoint.TargetRef != nil && endpoint.TargetRef.Kind == "Pod" {
+				podName = endpoint.TargetRef.Name
+			}
+			if existing, ok := pods[podName]; ok && podName != "" {
+				addresses, _ := existing["addresses"].([]string)
+				existing["addresses"] = append(addresses, endpoint.Addresses...)
+				continue
+			}
+
+			e := mapstr.M{
+				"addresses": append([]string{}, endpoint.Addresses...),
+				"ready":     ready,
+			}
+			if podName != "" {
+				e["pod"] = mapstr.M{"name": podName}
+				pods[podName] = e
+			}
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
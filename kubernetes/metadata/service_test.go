@@ -24,9 +24,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
 
@@ -138,7 +140,8 @@ func TestService_Generate(t *testing.T) {
 	}
 
 	cfg := config.NewConfig()
-	metagen := NewServiceMetadataGenerator(cfg, nil, nil, client)
+	metagen, err := NewServiceMetadataGenerator(cfg, nil, nil, client)
+	require.NoError(t, err)
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equal(t, test.output, metagen.Generate(test.input))
@@ -229,7 +232,8 @@ func TestService_GenerateFromName(t *testing.T) {
 		services := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err := services.Add(test.input)
 		require.NoError(t, err)
-		metagen := NewServiceMetadataGenerator(cfg, services, nil, client)
+		metagen, err := NewServiceMetadataGenerator(cfg, services, nil, client)
+		require.NoError(t, err)
 
 		accessor, err := meta.Accessor(test.input)
 		require.NoError(t, err)
@@ -315,11 +319,152 @@ func TestService_GenerateWithNamespace(t *testing.T) {
 		namespaces := cache.NewStore(cache.MetaNamespaceKeyFunc)
 		err = namespaces.Add(test.namespace)
 		require.NoError(t, err)
-		nsMeta := NewNamespaceMetadataGenerator(nsConfig, namespaces, client)
+		nsMeta, err := NewNamespaceMetadataGenerator(nsConfig, namespaces, client)
+		require.NoError(t, err)
 
-		metagen := NewServiceMetadataGenerator(nsConfig, services, nsMeta, client)
+		metagen, err := NewServiceMetadataGenerator(nsConfig, services, nsMeta, client)
+		require.NoError(t, err)
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equal(t, test.output, metagen.Generate(test.input))
 		})
 	}
 }
+
+func TestService_GenerateWithEndpoints(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	ready, notReady := true, false
+	appProtocol := "http"
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+		},
+		Spec: v1.ServiceSpec{
+			Type:        v1.ServiceTypeLoadBalancer,
+			ClusterIP:   "10.96.0.10",
+			ClusterIPs:  []string{"10.96.0.10", "fd00::10"},
+			ExternalIPs: []string{"203.0.113.10"},
+			Ports: []v1.ServicePort{
+				{
+					Name:        "http",
+					Port:        80,
+					Protocol:    v1.ProtocolTCP,
+					TargetPort:  intstr.FromString("web"),
+					NodePort:    30080,
+					AppProtocol: &appProtocol,
+				},
+				{
+					Name:       "metrics",
+					Port:       9090,
+					Protocol:   v1.ProtocolTCP,
+					TargetPort: intstr.FromInt32(9090),
+				},
+			},
+		},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{
+				Ingress: []v1.LoadBalancerIngress{
+					{IP: "198.51.100.1"},
+					{Hostname: "lb.example.com"},
+				},
+			},
+		},
+	}
+
+	serviceLabels := map[string]string{discoveryv1.LabelServiceName: name}
+	slices := []*discoveryv1.EndpointSlice{
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: name + "-ipv4", Namespace: defaultNs, Labels: serviceLabels},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints: []discoveryv1.Endpoint{
+				{
+					Addresses:  []string{"10.0.0.1"},
+					Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+					TargetRef:  &v1.ObjectReference{Kind: "Pod", Name: "pod-1"},
+				},
+				{
+					Addresses:  []string{"10.0.0.2"},
+					Conditions: discoveryv1.EndpointConditions{Ready: &notReady},
+					TargetRef:  &v1.ObjectReference{Kind: "Pod", Name: "pod-2"},
+				},
+			},
+		},
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: name + "-ipv6", Namespace: defaultNs, Labels: serviceLabels},
+			AddressType: discoveryv1.AddressTypeIPv6,
+			Endpoints: []discoveryv1.Endpoint{
+				{
+					Addresses:  []string{"fd00::1"},
+					Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+					TargetRef:  &v1.ObjectReference{Kind: "Pod", Name: "pod-1"},
+				},
+			},
+		},
+		{
+			// slices of other services are ignored
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "other",
+				Namespace: defaultNs,
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "other"},
+			},
+			Endpoints: []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.3"}}},
+		},
+	}
+	endpointSlices := cache.NewIndexer(cache.MetaNamespaceKeyFunc, kubernetes.EndpointSliceServiceIndexers())
+	for _, slice := range slices {
+		require.NoError(t, endpointSlices.Add(slice))
+	}
+
+	expected := mapstr.M{
+		"service": mapstr.M{
+			"name":         name,
+			"uid":          uid,
+			"type":         "LoadBalancer",
+			"cluster_ip":   "10.96.0.10",
+			"cluster_ips":  []string{"10.96.0.10", "fd00::10"},
+			"external_ips": []string{"203.0.113.10"},
+			"load_balancer": mapstr.M{
+				"ingress": []string{"198.51.100.1", "lb.example.com"},
+			},
+			"ports": mapstr.M{
+				"http": mapstr.M{
+					"port":         int32(80),
+					"protocol":     "TCP",
+					"target_port":  "web",
+					"node_port":    int32(30080),
+					"app_protocol": "http",
+				},
+				"metrics": mapstr.M{
+					"port":        int32(9090),
+					"protocol":    "TCP",
+					"target_port": "9090",
+				},
+			},
+		},
+		"namespace": defaultNs,
+	}
+
+	metagen, err := NewServiceMetadataGenerator(config.NewConfig(), nil, nil, client)
+	require.NoError(t, err)
+	assert.Equal(t, expected, metagen.GenerateK8s(svc))
+
+	metagen, err = NewServiceMetadataGenerator(config.NewConfig(), nil, nil, client, WithEndpointSlices(endpointSlices))
+	require.NoError(t, err)
+	meta := metagen.GenerateK8s(svc)
+	endpoints, err := meta.GetValue("service.endpoints")
+	require.NoError(t, err)
+	assert.Equal(t, []mapstr.M{
+		{
+			"addresses": []string{"10.0.0.1", "fd00::1"},
+			"ready":     true,
+			"pod":       mapstr.M{"name": "pod-1"},
+		},
+		{
+			"addresses": []string{"10.0.0.2"},
+			"ready":     false,
+			"pod":       mapstr.M{"name": "pod-2"},
+		},
+	}, endpoints)
+}
@@ -0,0 +1,130 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+type statefulset struct {
+	store    cache.Store
+	resource *Resource
+}
+
+// NewStatefulSetMetadataGenerator creates a metagen for statefulset resources
+func NewStatefulSetMetadataGenerator(cfg *config.C, statefulsets cache.Store, client k8s.Interface) (MetaGen, error) {
+	resource, err := NewResourceMetadataGenerator(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statefulset{
+		resource: resource,
+		store:    statefulsets,
+	}, nil
+}
+
+// Generate generates statefulset metadata from a resource object
+// Metadata map is in the following form:
+//
+//	{
+//		  "kubernetes": {},
+//	   "some.ecs.field": "asdf"
+//	}
+//
+// All Kubernetes fields that need to be stored under kubernetes. prefix are populated by
+// GenerateK8s method while fields that are part of ECS are generated by GenerateECS method
+func (ss *statefulset) Generate(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	ecsFields := ss.GenerateECS(obj)
+	meta := mapstr.M{
+		"kubernetes": ss.GenerateK8s(obj, opts...),
+	}
+	meta.DeepUpdate(ecsFields)
+	return meta
+}
+
+// GenerateECS generates statefulset ECS metadata from a resource object
+func (ss *statefulset) GenerateECS(obj kubernetes.Resource) mapstr.M {
+	return ss.resource.GenerateECS(obj)
+}
+
+// GenerateK8s generates statefulset metadata from a resource object
+func (ss *statefulset) GenerateK8s(obj kubernetes.Resource, opts ...FieldOptions) mapstr.M {
+	return ss.resource.finishK8s(ss.generateK8s(obj), opts...)
+}
+
+// generateK8s generates statefulset metadata from a resource object, without applying the field policy and the options
+func (ss *statefulset) generateK8s(obj kubernetes.Resource) mapstr.M {
+	_, ok := obj.(metav1.Object)
+	if !ok {
+		return nil
+	}
+
+	out := ss.resource.generateK8s("statefulset", obj)
+
+	// Metadata-only resources don't have a spec nor a status
+	sts, ok := obj.(*kubernetes.StatefulSet)
+	if !ok {
+		return out
+	}
+
+	addWorkloadFields(out, "statefulset", workloadReplicas{
+		desired:   sts.Spec.Replicas,
+		current:   sts.Status.Replicas,
+		ready:     sts.Status.ReadyReplicas,
+		available: sts.Status.AvailableReplicas,
+		updated:   sts.Status.UpdatedReplicas,
+	}, string(sts.Spec.UpdateStrategy.Type))
+	addSelectors(out, sts.Spec.Selector, ss.resource.config.LabelsDedot)
+
+	return out
+}
+
+// GenerateFromName generates statefulset metadata from a statefulset name
+func (ss *statefulset) GenerateFromName(name string, opts ...FieldOptions) mapstr.M {
+	if ss.store == nil {
+		return nil
+	}
+
+	if obj, ok, _ := ss.store.GetByKey(name); ok {
+		res, ok := obj.(kubernetes.Resource)
+		if !ok {
+			return nil
+		}
+
+		return ss.GenerateK8s(res, opts...)
+	}
+
+	return nil
+}
+
+// generateNestedFromName generates statefulset metadata from a statefulset name to embed it in the metadata of other
+// resources, without applying the field policy
+func (ss *statefulset) generateNestedFromName(name string, opts ...FieldOptions) mapstr.M {
+	obj, ok := getFromStore(ss.store, name)
+	if !ok {
+		return nil
+	}
+	return applyFieldOptions(ss.generateK8s(obj), opts)
+}
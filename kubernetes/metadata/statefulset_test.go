@@ -0,0 +1,98 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestStatefulSet_Generate(t *testing.T) {
+	client := k8sfake.NewSimpleClientset()
+	replicas := int32(2)
+	input := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			UID:       types.UID(uid),
+			Namespace: defaultNs,
+			Labels: map[string]string{
+				"foo": "bar",
+			},
+		},
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: "apps/v1",
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": "mysql",
+				},
+			},
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.OnDeleteStatefulSetStrategyType,
+			},
+		},
+		Status: appsv1.StatefulSetStatus{
+			Replicas:          2,
+			ReadyReplicas:     2,
+			AvailableReplicas: 1,
+			UpdatedReplicas:   2,
+		},
+	}
+
+	statefulsets := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, statefulsets.Add(input))
+	metagen, err := NewStatefulSetMetadataGenerator(config.NewConfig(), statefulsets, client)
+	require.NoError(t, err)
+
+	expected := mapstr.M{
+		"statefulset": mapstr.M{
+			"name": name,
+			"uid":  uid,
+			"replicas": mapstr.M{
+				"desired":   int32(2),
+				"current":   int32(2),
+				"ready":     int32(2),
+				"available": int32(1),
+				"updated":   int32(2),
+			},
+			"strategy": "OnDelete",
+		},
+		"selectors": mapstr.M{
+			"app": "mysql",
+		},
+		"labels": mapstr.M{
+			"foo": "bar",
+		},
+		"namespace": defaultNs,
+	}
+	assert.Equal(t, mapstr.M{"kubernetes": expected}, metagen.Generate(input))
+	assert.Equal(t, expected, metagen.GenerateFromName(defaultNs+"/"+name))
+}
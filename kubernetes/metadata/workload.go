@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/safemapstr"
+)
+
+// workloadReplicas holds the replica counts of a workload
+type workloadReplicas struct {
+	desired   *int32
+	current   int32
+	ready     int32
+	available int32
+	updated   int32
+}
+
+// addWorkloadFields adds the replica counts and the update strategy of a workload under its kind
+func addWorkloadFields(out mapstr.M, kind string, replicas workloadReplicas, strategy string) {
+	replicasMap := mapstr.M{
+		"current":   replicas.current,
+		"ready":     replicas.ready,
+		"available": replicas.available,
+		"updated":   replicas.updated,
+	}
+	if replicas.desired != nil {
+		replicasMap["desired"] = *replicas.desired
+	}
+	_, _ = out.Put(kind+".replicas", replicasMap)
+
+	if strategy != "" {
+		_, _ = out.Put(kind+".strategy", strategy)
+	}
+}
+
+// addSelectors adds the match labels of a label selector as selectors
+func addSelectors(out mapstr.M, selector *metav1.LabelSelector, dedot bool) {
+	if selector == nil || len(selector.MatchLabels) == 0 {
+		return
+	}
+	selectorMap := GenerateMap(selector.MatchLabels, dedot)
+	if len(selectorMap) != 0 {
+		_ = safemapstr.Put(out, "selectors", selectorMap)
+	}
+}
@@ -0,0 +1,157 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kubernetes
+
+import (
+	"sort"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ServiceSelectorIndex is the name of the index of services by the label pairs of their selectors.
+// It can be added to a service watcher with ServiceSelectorIndexers to speed up ServicesSelectingPod.
+const ServiceSelectorIndex = "service_selector"
+
+// ServiceSelectorIndexers returns the indexers needed by ServicesSelectingPod
+func ServiceSelectorIndexers() cache.Indexers {
+	return cache.Indexers{
+		ServiceSelectorIndex: ServiceSelectorIndexFunc,
+	}
+}
+
+// ServiceSelectorIndexFunc indexes services by each namespace/key=value pair of their selector.
+// Services without selector don't select any pod, so they are not indexed.
+func ServiceSelectorIndexFunc(obj any) ([]string, error) {
+	svc, ok := obj.(*Service)
+	if !ok {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(svc.Spec.Selector))
+	for key, value := range svc.Spec.Selector {
+		keys = append(keys, selectorIndexKey(svc.Namespace, key, value))
+	}
+	return keys, nil
+}
+
+// ServicesSelectingPod returns the services of the store whose selector matches the labels of the pod,
+// sorted by name. The ServiceSelectorIndex of the store is used when available, otherwise all the
+// services in the store are checked.
+func ServicesSelectingPod(store cache.Store, pod *Pod) []*Service {
+	if store == nil || pod == nil || len(pod.Labels) == 0 {
+		return nil
+	}
+
+	var candidates []any
+	if indexer, ok := store.(cache.Indexer); ok && indexer.GetIndexers()[ServiceSelectorIndex] != nil {
+		seen := map[any]bool{}
+		for key, value := range pod.Labels {
+			objs, err := indexer.ByIndex(ServiceSelectorIndex, selectorIndexKey(pod.Namespace, key, value))
+			if err != nil {
+				return nil
+			}
+			for _, obj := range objs {
+				if !seen[obj] {
+					seen[obj] = true
+					candidates = append(candidates, obj)
+				}
+			}
+		}
+	} else {
+		candidates = store.List()
+	}
+
+	podLabels := labels.Set(pod.Labels)
+	var services []*Service
+	for _, obj := range candidates {
+		svc, ok := obj.(*Service)
+		if !ok || svc.Namespace != pod.Namespace || len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(svc.Spec.Selector).Matches(podLabels) {
+			services = append(services, svc)
+		}
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	return services
+}
+
+func selectorIndexKey(namespace, key, value string) string {
+	return namespace + "/" + key + "=" + value
+}
+
+// EndpointSliceServiceIndex is the name of the index of EndpointSlices by the namespace/name of their service.
+// It can be added to an EndpointSlice watcher with EndpointSliceServiceIndexers to speed up EndpointSlicesOfService.
+const EndpointSliceServiceIndex = "endpointslice_service"
+
+// EndpointSliceServiceIndexers returns the indexers needed by EndpointSlicesOfService
+func EndpointSliceServiceIndexers() cache.Indexers {
+	return cache.Indexers{
+		EndpointSliceServiceIndex: EndpointSliceServiceIndexFunc,
+	}
+}
+
+// EndpointSliceServiceIndexFunc indexes EndpointSlices by the namespace/name of the service in their
+// kubernetes.io/service-name label. EndpointSlices without the label are not indexed.
+func EndpointSliceServiceIndexFunc(obj any) ([]string, error) {
+	slice, ok := obj.(*EndpointSlice)
+	if !ok {
+		return nil, nil
+	}
+	name := slice.Labels[discoveryv1.LabelServiceName]
+	if name == "" {
+		return nil, nil
+	}
+	return []string{slice.Namespace + "/" + name}, nil
+}
+
+// EndpointSlicesOfService returns the EndpointSlices of the store that belong to the service, sorted by name.
+// The EndpointSliceServiceIndex of the store is used when available, otherwise all the EndpointSlices
+// in the store are checked.
+func EndpointSlicesOfService(store cache.Store, svc *Service) []*EndpointSlice {
+	if store == nil || svc == nil {
+		return nil
+	}
+
+	var candidates []any
+	if indexer, ok := store.(cache.Indexer); ok && indexer.GetIndexers()[EndpointSliceServiceIndex] != nil {
+		objs, err := indexer.ByIndex(EndpointSliceServiceIndex, svc.Namespace+"/"+svc.Name)
+		if err != nil {
+			return nil
+		}
+		candidates = objs
+	} else {
+		candidates = store.List()
+	}
+
+	var slices []*EndpointSlice
+	for _, obj := range candidates {
+		slice, ok := obj.(*EndpointSlice)
+		if ok && slice.Namespace == svc.Namespace && slice.Labels[discoveryv1.LabelServiceName] == svc.Name {
+			slices = append(slices, slice)
+		}
+	}
+	sort.Slice(slices, func(i, j int) bool {
+		return slices[i].Name < slices[j].Name
+	})
+	return slices
+}
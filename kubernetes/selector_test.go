@@ -0,0 +1,127 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestServicesSelectingPod(t *testing.T) {
+	newService := func(namespace, name string, selector map[string]string) *Service {
+		svc := &Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+		}
+		svc.Spec.Selector = selector
+		return svc
+	}
+	services := []*Service{
+		newService("default", "web", map[string]string{"app": "nginx"}),
+		newService("default", "web-canary", map[string]string{"app": "nginx", "track": "canary"}),
+		newService("default", "api", map[string]string{"app": "api"}),
+		newService("default", "external", nil),
+		newService("other", "web", map[string]string{"app": "nginx"}),
+	}
+
+	pod := &Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx",
+			Namespace: "default",
+			Labels: map[string]string{
+				"app":   "nginx",
+				"track": "stable",
+			},
+		},
+	}
+
+	stores := map[string]cache.Store{
+		"store":   cache.NewStore(cache.MetaNamespaceKeyFunc),
+		"indexer": cache.NewIndexer(cache.MetaNamespaceKeyFunc, ServiceSelectorIndexers()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			for _, svc := range services {
+				require.NoError(t, store.Add(svc))
+			}
+
+			selecting := ServicesSelectingPod(store, pod)
+			require.Len(t, selecting, 1)
+			assert.Equal(t, "web", selecting[0].Name)
+			assert.Equal(t, "default", selecting[0].Namespace)
+
+			pod.Labels["track"] = "canary"
+			selecting = ServicesSelectingPod(store, pod)
+			require.Len(t, selecting, 2)
+			assert.Equal(t, "web", selecting[0].Name)
+			assert.Equal(t, "web-canary", selecting[1].Name)
+			pod.Labels["track"] = "stable"
+
+			assert.Empty(t, ServicesSelectingPod(store, &Pod{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled", Namespace: "default"}}))
+		})
+	}
+}
+
+func TestEndpointSlicesOfService(t *testing.T) {
+	newSlice := func(namespace, name, service string) *EndpointSlice {
+		slice := &EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+		}
+		if service != "" {
+			slice.Labels = map[string]string{discoveryv1.LabelServiceName: service}
+		}
+		return slice
+	}
+	slices := []*EndpointSlice{
+		newSlice("default", "web-ipv6", "web"),
+		newSlice("default", "web-ipv4", "web"),
+		newSlice("default", "api-1", "api"),
+		newSlice("default", "unmanaged", ""),
+		newSlice("other", "web-1", "web"),
+	}
+	svc := &Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+
+	stores := map[string]cache.Store{
+		"store":   cache.NewStore(cache.MetaNamespaceKeyFunc),
+		"indexer": cache.NewIndexer(cache.MetaNamespaceKeyFunc, EndpointSliceServiceIndexers()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			for _, slice := range slices {
+				require.NoError(t, store.Add(slice))
+			}
+
+			found := EndpointSlicesOfService(store, svc)
+			require.Len(t, found, 2)
+			assert.Equal(t, "web-ipv4", found[0].Name)
+			assert.Equal(t, "web-ipv6", found[1].Name)
+
+			assert.Empty(t, EndpointSlicesOfService(store, &Service{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}}))
+		})
+	}
+}
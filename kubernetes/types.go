@@ -22,9 +22,13 @@ import (
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	eventsv1 "k8s.io/api/events/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -61,6 +65,9 @@ type ContainerPort = v1.ContainerPort
 // Event data
 type Event = v1.Event
 
+// EventV1 data of the events.k8s.io/v1 API
+type EventV1 = eventsv1.Event
+
 // PodContainerStatus data
 type PodContainerStatus = v1.ContainerStatus
 
@@ -112,6 +119,18 @@ type ClusterRoleBinding = rbacv1.ClusterRoleBinding
 // NetworkPolicy data
 type NetworkPolicy = networkingv1.NetworkPolicy
 
+// Ingress data
+type Ingress = networkingv1.Ingress
+
+// EndpointSlice data
+type EndpointSlice = discoveryv1.EndpointSlice
+
+// PodDisruptionBudget data
+type PodDisruptionBudget = policyv1.PodDisruptionBudget
+
+// HorizontalPodAutoscaler data
+type HorizontalPodAutoscaler = autoscalingv2.HorizontalPodAutoscaler
+
 const (
 	// PodPending phase
 	PodPending = v1.PodPending
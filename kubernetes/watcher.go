@@ -20,6 +20,8 @@ package kubernetes
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -70,12 +72,24 @@ type Watcher interface {
 
 	// CachedObject returns the old object before change during the last updated event
 	CachedObject() runtime.Object
+
+	// Status returns the health and sync status of the watcher
+	Status() WatcherStatus
 }
 
 // WatchOptions controls watch behaviors
 type WatchOptions struct {
-	// SyncTimeout is a timeout for listing historical resources
+	// SyncTimeout is used as the resync period of the informer when ResyncPeriod is not set.
+	//
+	// Deprecated: Use ResyncPeriod to configure the resync period and InitialSyncTimeout to bound the
+	// time Start waits for the initial listing of resources.
 	SyncTimeout time.Duration
+	// ResyncPeriod is the period of the informer resyncs, use 0 to disable resyncs
+	ResyncPeriod time.Duration
+	// InitialSyncTimeout bounds the time Start waits for the initial listing of resources, use 0 to wait
+	// until the cache is synced. When it expires Start returns a *SyncTimeoutError while the informer keeps
+	// retrying in the background and events are processed as soon as they are received.
+	InitialSyncTimeout time.Duration
 	// Node is used for filtering watched resource to given node, use "" for all nodes
 	Node string
 	// Namespace is used for filtering watched resource to given namespace, use "" for all namespaces
@@ -85,8 +99,81 @@ type WatchOptions struct {
 	IsUpdated func(old, new any) bool
 	// HonorReSyncs allows resync events to be requeued on the worker
 	HonorReSyncs bool
+	// Metrics receives the health metrics of the watcher, metrics are discarded if nil
+	Metrics WatcherMetrics
+}
+
+// resyncPeriod returns the resync period of the informer, falling back to the deprecated SyncTimeout
+func (o WatchOptions) resyncPeriod() time.Duration {
+	if o.ResyncPeriod != 0 {
+		return o.ResyncPeriod
+	}
+	return o.SyncTimeout
+}
+
+// SyncTimeoutError is returned by Start when the cache of the watcher is not synced within
+// the InitialSyncTimeout. The watcher keeps running and syncs in the background.
+type SyncTimeoutError struct {
+	Timeout time.Duration
+}
+
+// Error implements the error interface
+func (e *SyncTimeoutError) Error() string {
+	return fmt.Sprintf("kubernetes informer unable to sync cache in %s, syncing in the background", e.Timeout)
+}
+
+// WatcherStatus reports the health and sync status of a watcher
+type WatcherStatus struct {
+	// Name of the watcher as given to NewNamedWatcher
+	Name string
+	// Synced is true once the initial listing of resources is done
+	Synced bool
+	// LastSyncTime is the time the cache was last synced
+	LastSyncTime time.Time
+	// WatchErrors is the number of errors returned by the watch of the informer
+	WatchErrors int64
+	// LastWatchError is the last error returned by the watch of the informer
+	LastWatchError error
+	// LastWatchErrorTime is the time the last watch error happened
+	LastWatchErrorTime time.Time
+	// QueueLength is the number of events waiting to be processed
+	QueueLength int
+	// StoreSize is the number of objects in the store of the watcher
+	StoreSize int
 }
 
+// WatcherMetrics is a sink for the metrics of a watcher, each method receives the name of the watcher
+type WatcherMetrics interface {
+	// Synced is called when the cache of the watcher is synced
+	Synced(name string, t time.Time)
+	// WatchError is called for every error returned by the watch of the informer
+	WatchError(name string, err error)
+	// QueueLength is called with the number of events waiting to be processed
+	QueueLength(name string, length int)
+	// ProcessingLatency is called with the time spent handling an event
+	ProcessingLatency(name string, latency time.Duration)
+	// StoreSize is called with the number of objects in the store when objects are added or deleted
+	StoreSize(name string, size int)
+}
+
+// NoOpWatcherMetrics discards all the metrics of a watcher
+type NoOpWatcherMetrics struct{}
+
+// Synced does a no-op on a cache sync
+func (NoOpWatcherMetrics) Synced(string, time.Time) {}
+
+// WatchError does a no-op on a watch error
+func (NoOpWatcherMetrics) WatchError(string, error) {}
+
+// QueueLength does a no-op on a queue length update
+func (NoOpWatcherMetrics) QueueLength(string, int) {}
+
+// ProcessingLatency does a no-op on a processed event
+func (NoOpWatcherMetrics) ProcessingLatency(string, time.Duration) {}
+
+// StoreSize does a no-op on a store size update
+func (NoOpWatcherMetrics) StoreSize(string, int) {}
+
 type item struct {
 	object    any
 	objectRaw any
@@ -94,6 +181,7 @@ type item struct {
 }
 
 type watcher struct {
+	name         string
 	client       kubernetes.Interface
 	informer     cache.SharedInformer
 	store        cache.Store
@@ -103,6 +191,19 @@ type watcher struct {
 	handler      ResourceEventHandler
 	logger       *logp.Logger
 	cachedObject runtime.Object
+	metrics      WatcherMetrics
+
+	initialSyncTimeout time.Duration
+
+	watchErrors atomic.Int64
+	// storeSize counts the objects added to and deleted from the store, so its size doesn't need
+	// to be computed from its keys on every event
+	storeSize atomic.Int64
+
+	statusMutex        sync.RWMutex
+	lastSyncTime       time.Time
+	lastWatchError     error
+	lastWatchErrorTime time.Time
 }
 
 // NewWatcher initializes the watcher client to provide a events handler for
@@ -162,8 +263,13 @@ func NewNamedWatcherWithInformer(
 		}
 	}
 
+	if opts.Metrics == nil {
+		opts.Metrics = NoOpWatcherMetrics{}
+	}
+
 	ctx, cancel := context.WithCancel(context.TODO())
 	w := &watcher{
+		name:         name,
 		client:       client,
 		informer:     informer,
 		store:        store,
@@ -173,13 +279,24 @@ func NewNamedWatcherWithInformer(
 		stop:         cancel,
 		logger:       logger.Named("kubernetes"),
 		handler:      NoOpEventHandlerFuncs{},
+		metrics:      opts.Metrics,
+
+		initialSyncTimeout: opts.InitialSyncTimeout,
+	}
+
+	// The watch error handler of an informer can only be set before it is started, watchers of informers
+	// started without one of their watchers don't track watch errors.
+	if err := registerWatchErrorHandler(informer, w); err != nil {
+		w.logger.Debugf("Watch errors of watcher %s are not tracked: %v", name, err)
 	}
 
 	_, err := w.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(o any) {
+			w.storeSize.Add(1)
 			w.enqueue(o, add)
 		},
 		DeleteFunc: func(o any) {
+			w.storeSize.Add(-1)
 			w.enqueue(o, delete)
 		},
 		UpdateFunc: func(o, n any) {
@@ -206,6 +323,7 @@ func NewNamedWatcherWithInformer(
 		},
 	})
 	if err != nil {
+		unregisterWatchErrorHandler(informer, w)
 		return nil, err
 	}
 
@@ -284,26 +402,159 @@ func (w *watcher) CachedObject() runtime.Object {
 func (w *watcher) Start() error {
 	go w.informer.Run(w.ctx.Done())
 
-	if !cache.WaitForCacheSync(w.ctx.Done(), w.informer.HasSynced) {
-		return fmt.Errorf("kubernetes informer unable to sync cache")
+	syncCtx := w.ctx
+	if w.initialSyncTimeout > 0 {
+		var cancel context.CancelFunc
+		syncCtx, cancel = context.WithTimeout(w.ctx, w.initialSyncTimeout)
+		defer cancel()
+	}
+
+	if !cache.WaitForCacheSync(syncCtx.Done(), w.informer.HasSynced) {
+		if w.ctx.Err() != nil {
+			return fmt.Errorf("kubernetes informer unable to sync cache")
+		}
+
+		// The initial sync timed out, the informer keeps retrying so keep waiting in the background
+		// and process events as soon as they are received.
+		go func() {
+			if cache.WaitForCacheSync(w.ctx.Done(), w.informer.HasSynced) {
+				w.logger.Debugf("cache sync done")
+				w.synced(time.Now())
+			}
+		}()
+		w.startProcessing()
+
+		return &SyncTimeoutError{Timeout: w.initialSyncTimeout}
 	}
 
 	w.logger.Debugf("cache sync done")
+	w.synced(time.Now())
+	w.startProcessing()
+
+	return nil
+}
 
+// startProcessing starts the worker that processes the events of the queue
+func (w *watcher) startProcessing() {
 	// Wrap the process function with wait.Until so that if the controller crashes, it starts up again after a second.
 	go wait.Until(func() {
 		for w.process(w.ctx) {
 		}
 	}, time.Second*1, w.ctx.Done())
-
-	return nil
 }
 
 func (w *watcher) Stop() {
+	unregisterWatchErrorHandler(w.informer, w)
 	w.queue.ShutDown()
 	w.stop()
 }
 
+// Status returns the health and sync status of the watcher
+func (w *watcher) Status() WatcherStatus {
+	w.statusMutex.RLock()
+	defer w.statusMutex.RUnlock()
+
+	return WatcherStatus{
+		Name:               w.name,
+		Synced:             w.informer.HasSynced(),
+		LastSyncTime:       w.lastSyncTime,
+		WatchErrors:        w.watchErrors.Load(),
+		LastWatchError:     w.lastWatchError,
+		LastWatchErrorTime: w.lastWatchErrorTime,
+		QueueLength:        w.queue.Len(),
+		StoreSize:          int(w.storeSize.Load()),
+	}
+}
+
+// synced records the time the cache of the watcher was synced
+func (w *watcher) synced(t time.Time) {
+	w.statusMutex.Lock()
+	w.lastSyncTime = t
+	w.statusMutex.Unlock()
+
+	w.metrics.Synced(w.name, t)
+}
+
+// watchErrorDispatcher is the watch error handler of an informer. Informers can be shared by several
+// watchers but have a single watch error handler, so the dispatcher hands the errors to all of them.
+type watchErrorDispatcher struct {
+	// watchers holds the watchers of the informer, as keys
+	watchers sync.Map
+	count    int
+}
+
+var (
+	watchErrorDispatchersMutex sync.Mutex
+	// watchErrorDispatchers holds the dispatchers of the informers with running watchers
+	watchErrorDispatchers sync.Map
+)
+
+// registerWatchErrorHandler adds the watcher to the watch error dispatcher of the informer, the dispatcher
+// is set as watch error handler of the informer by its first watcher, this fails if the informer was already started.
+func registerWatchErrorHandler(informer cache.SharedInformer, w *watcher) error {
+	watchErrorDispatchersMutex.Lock()
+	defer watchErrorDispatchersMutex.Unlock()
+
+	var dispatcher *watchErrorDispatcher
+	if value, ok := watchErrorDispatchers.Load(informer); ok {
+		dispatcher, _ = value.(*watchErrorDispatcher)
+	} else {
+		dispatcher = &watchErrorDispatcher{}
+		if err := informer.SetWatchErrorHandler(dispatcher.handle); err != nil {
+			return err
+		}
+		watchErrorDispatchers.Store(informer, dispatcher)
+	}
+
+	if _, loaded := dispatcher.watchers.LoadOrStore(w, struct{}{}); !loaded {
+		dispatcher.count++
+	}
+	return nil
+}
+
+// unregisterWatchErrorHandler removes the watcher from the watch error dispatcher of the informer,
+// the dispatcher is forgotten when it has no watchers, so informers can be garbage collected.
+func unregisterWatchErrorHandler(informer cache.SharedInformer, w *watcher) {
+	watchErrorDispatchersMutex.Lock()
+	defer watchErrorDispatchersMutex.Unlock()
+
+	value, ok := watchErrorDispatchers.Load(informer)
+	if !ok {
+		return
+	}
+	dispatcher, _ := value.(*watchErrorDispatcher)
+	if _, loaded := dispatcher.watchers.LoadAndDelete(w); loaded {
+		dispatcher.count--
+	}
+	if dispatcher.count == 0 {
+		watchErrorDispatchers.Delete(informer)
+	}
+}
+
+// handle hands the watch errors of the informer to its watchers, and then to the default handler
+// of client-go, that logs them.
+func (d *watchErrorDispatcher) handle(r *cache.Reflector, err error) {
+	d.watchers.Range(func(key, _ any) bool {
+		if w, ok := key.(*watcher); ok {
+			w.watchError(err)
+		}
+		return true
+	})
+	cache.DefaultWatchErrorHandler(r, err)
+}
+
+// watchError keeps track of the errors returned by the watch of the informer
+func (w *watcher) watchError(err error) {
+	w.watchErrors.Add(1)
+
+	w.statusMutex.Lock()
+	w.lastWatchError = err
+	w.lastWatchErrorTime = time.Now()
+	w.statusMutex.Unlock()
+
+	w.metrics.WatchError(w.name, err)
+}
+
 // enqueue takes the most recent object that was received, figures out the namespace/name of the object
 // and adds it to the work queue for processing.
 func (w *watcher) enqueue(obj any, state string) {
@@ -317,6 +568,7 @@ func (w *watcher) enqueue(obj any, state string) {
 		obj = deleted.Obj
 	}
 	w.queue.Add(&item{key, obj, state})
+	w.metrics.QueueLength(w.name, w.queue.Len())
 }
 
 // cacheObject updates watcher with the old version of cache objects before change during update events
@@ -336,6 +588,9 @@ func (w *watcher) process(_ context.Context) bool {
 	}
 	defer w.queue.Done(obj)
 
+	start := time.Now()
+	w.metrics.QueueLength(w.name, w.queue.Len())
+
 	var entry *item
 	var ok bool
 	if entry, ok = obj.(*item); !ok {
@@ -358,6 +613,7 @@ func (w *watcher) process(_ context.Context) bool {
 			w.logger.Debugf("Object %+v was not found in the store, deleting anyway!", key)
 			// delete anyway in order to clean states
 			w.handler.OnDelete(entry.objectRaw)
+			w.processed(entry.state, start)
 		}
 		return true
 	}
@@ -370,6 +626,16 @@ func (w *watcher) process(_ context.Context) bool {
 	case delete:
 		w.handler.OnDelete(o)
 	}
+	w.processed(entry.state, start)
 
 	return true
 }
+
+// processed reports the metrics of an event handled by the watcher
+func (w *watcher) processed(state string, start time.Time) {
+	w.metrics.ProcessingLatency(w.name, time.Since(start))
+	// The size of the store only changes on additions and deletions
+	if state != update {
+		w.metrics.StoreSize(w.name, int(w.storeSize.Load()))
+	}
+}
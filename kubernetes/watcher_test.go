@@ -18,13 +18,19 @@
 package kubernetes
 
 import (
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
 	cachetest "k8s.io/client-go/tools/cache/testing"
@@ -165,3 +171,214 @@ func TestCachedObject(t *testing.T) {
 		assert.Equal(collectT, namespace, watcher.CachedObject())
 	}, time.Second*5, time.Millisecond)
 }
+
+type testWatcherMetrics struct {
+	sync.Mutex
+	synced      int
+	watchErrors int
+	processed   int
+	storeSize   int
+}
+
+func (m *testWatcherMetrics) Synced(string, time.Time) {
+	m.Lock()
+	defer m.Unlock()
+	m.synced++
+}
+
+func (m *testWatcherMetrics) WatchError(string, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.watchErrors++
+}
+
+func (m *testWatcherMetrics) QueueLength(string, int) {}
+
+func (m *testWatcherMetrics) ProcessingLatency(string, time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.processed++
+}
+
+func (m *testWatcherMetrics) StoreSize(_ string, size int) {
+	m.Lock()
+	defer m.Unlock()
+	m.storeSize = size
+}
+
+func TestWatcherStatus(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	listWatch := cachetest.NewFakeControllerSource()
+	resource := &Pod{}
+	informer := cache.NewSharedInformer(listWatch, resource, 0)
+	metrics := &testWatcherMetrics{}
+	watcher, err := NewNamedWatcherWithInformer("test", client, resource, informer, logptest.NewTestingLogger(t, ""), WatchOptions{Metrics: metrics})
+	require.NoError(t, err)
+
+	status := watcher.Status()
+	assert.Equal(t, "test", status.Name)
+	assert.False(t, status.Synced)
+	assert.True(t, status.LastSyncTime.IsZero())
+
+	require.NoError(t, watcher.Start())
+	defer watcher.Stop()
+
+	status = watcher.Status()
+	assert.True(t, status.Synced)
+	assert.False(t, status.LastSyncTime.IsZero())
+	assert.Equal(t, int64(0), status.WatchErrors)
+
+	listWatch.Add(&Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			UID:             types.UID("poduid"),
+			Namespace:       "test",
+			ResourceVersion: "1",
+		},
+	})
+	assert.EventuallyWithT(t, func(collectT *assert.CollectT) {
+		assert.Equal(collectT, 1, watcher.Status().StoreSize)
+		assert.Equal(collectT, 0, watcher.Status().QueueLength)
+
+		metrics.Lock()
+		defer metrics.Unlock()
+		assert.Equal(collectT, 1, metrics.synced)
+		assert.Equal(collectT, 1, metrics.processed)
+		assert.Equal(collectT, 1, metrics.storeSize)
+	}, time.Second*5, time.Millisecond)
+
+	listWatch.Delete(&Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			UID:             types.UID("poduid"),
+			Namespace:       "test",
+			ResourceVersion: "2",
+		},
+	})
+	assert.EventuallyWithT(t, func(collectT *assert.CollectT) {
+		assert.Equal(collectT, 0, watcher.Status().StoreSize)
+
+		metrics.Lock()
+		defer metrics.Unlock()
+		assert.Equal(collectT, 0, metrics.storeSize)
+	}, time.Second*5, time.Millisecond)
+}
+
+func TestWatcherStatusWatchErrors(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &corev1.PodList{}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return nil, errors.New("watch failed")
+		},
+	}
+	resource := &Pod{}
+	informer := cache.NewSharedInformer(listWatch, resource, 0)
+	metrics := &testWatcherMetrics{}
+	watcher, err := NewNamedWatcherWithInformer("test", client, resource, informer, logptest.NewTestingLogger(t, ""), WatchOptions{Metrics: metrics})
+	require.NoError(t, err)
+
+	require.NoError(t, watcher.Start())
+	defer watcher.Stop()
+
+	assert.EventuallyWithT(t, func(collectT *assert.CollectT) {
+		status := watcher.Status()
+		assert.Positive(collectT, status.WatchErrors)
+		assert.EqualError(collectT, status.LastWatchError, "watch failed")
+
+		metrics.Lock()
+		defer metrics.Unlock()
+		assert.Positive(collectT, metrics.watchErrors)
+	}, time.Second*5, time.Millisecond)
+}
+
+func TestWatcherStatusWatchErrorsSharedInformer(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &corev1.PodList{}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return nil, errors.New("watch failed")
+		},
+	}
+	resource := &Pod{}
+	informer := cache.NewSharedInformer(listWatch, resource, 0)
+	first, err := NewNamedWatcherWithInformer("first", client, resource, informer, logptest.NewTestingLogger(t, ""), WatchOptions{})
+	require.NoError(t, err)
+	second, err := NewNamedWatcherWithInformer("second", client, resource, informer, logptest.NewTestingLogger(t, ""), WatchOptions{})
+	require.NoError(t, err)
+	defer second.Stop()
+
+	require.NoError(t, first.Start())
+	defer first.Stop()
+
+	// Watchers can be created for informers that are already running, without tracking watch errors
+	started, err := NewNamedWatcherWithInformer("started", client, resource, informer, logptest.NewTestingLogger(t, ""), WatchOptions{})
+	require.NoError(t, err)
+	defer started.Stop()
+
+	assert.EventuallyWithT(t, func(collectT *assert.CollectT) {
+		assert.Positive(collectT, first.Status().WatchErrors)
+		assert.Positive(collectT, second.Status().WatchErrors)
+	}, time.Second*5, time.Millisecond)
+	assert.Zero(t, started.Status().WatchErrors)
+}
+
+func TestWatcherInitialSyncTimeout(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	var reachable atomic.Bool
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			if !reachable.Load() {
+				return nil, errors.New("api server unreachable")
+			}
+			return &corev1.PodList{
+				Items: []corev1.Pod{{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "test",
+					},
+				}},
+			}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	}
+	resource := &Pod{}
+	informer := cache.NewSharedInformer(listWatch, resource, 0)
+	watcher, err := NewNamedWatcherWithInformer("test", client, resource, informer,
+		logptest.NewTestingLogger(t, ""),
+		WatchOptions{InitialSyncTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+
+	var added atomic.Bool
+	watcher.AddEventHandler(ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			added.Store(true)
+		},
+	})
+
+	err = watcher.Start()
+	defer watcher.Stop()
+	var timeoutErr *SyncTimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, 100*time.Millisecond, timeoutErr.Timeout)
+	assert.False(t, watcher.Status().Synced)
+
+	// the informer keeps retrying and catches up once the API server is reachable
+	reachable.Store(true)
+	assert.Eventually(t, func() bool {
+		status := watcher.Status()
+		return status.Synced && !status.LastSyncTime.IsZero() && added.Load()
+	}, time.Second*5, time.Millisecond)
+}
+
+func TestWatchOptionsResyncPeriod(t *testing.T) {
+	assert.Equal(t, time.Duration(0), WatchOptions{}.resyncPeriod())
+	assert.Equal(t, time.Minute, WatchOptions{SyncTimeout: time.Minute}.resyncPeriod())
+	assert.Equal(t, time.Second, WatchOptions{SyncTimeout: time.Minute, ResyncPeriod: time.Second}.resyncPeriod())
+}
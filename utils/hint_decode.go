@@ -0,0 +1,311 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// HintFieldError is a hint that couldn't be decoded into a field
+type HintFieldError struct {
+	// Hint is the path of the hint in the hints, like metrics.period or metrics.1.period
+	Hint string
+	// Field is the path of the field in the decoded struct, like Period or Modules[0].Period
+	Field string
+	Value any
+	Err   error
+}
+
+// Error returns a message describing the error
+func (e HintFieldError) Error() string {
+	return fmt.Sprintf("cannot decode hint %s into %s: %v", e.Hint, e.Field, e.Err)
+}
+
+// Unwrap returns the decoding error
+func (e HintFieldError) Unwrap() error {
+	return e.Err
+}
+
+// HintDecodeErrors are the errors of all the hints that couldn't be decoded by DecodeHints
+type HintDecodeErrors []HintFieldError
+
+// Error returns the messages of all the errors
+func (e HintDecodeErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	mapStrType   = reflect.TypeOf(mapstr.M{})
+)
+
+// DecodeHints decodes the hints under key into out, that must be a pointer to a struct or to a slice of
+// structs. Slices get a struct for each config returned by GetHintsAsList, so for each enumerated block
+// and for the default block.
+//
+// Fields are decoded from the hint named in their hint tag, like `hint:"period"`, untagged fields are ignored.
+// Hints are decoded according to the type of the field:
+//   - strings, bools, numbers and time.Duration are parsed from the value
+//   - []string are comma separated lists
+//   - structs are decoded from nested hints, like multiline.pattern into a struct with a pattern field
+//   - slices of structs are decoded from the enumerated blocks of nested hints, like processors.1.* and
+//     processors.2.*, in the same order as GetConfigs
+//   - mapstr.M and maps are decoded from nested hints or from JSON values
+//   - any type is decoded from a JSON value with the json option, like `hint:"raw,json"`
+//
+// Fields whose hint is not set are left unchanged, so defaults can be set before decoding.
+// Errors of all fields are returned as HintDecodeErrors.
+func DecodeHints(hints mapstr.M, key string, out any) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.New("decoding hints requires a non-nil pointer")
+	}
+	v = v.Elem()
+
+	d := &hintsDecoder{}
+	switch {
+	case v.Kind() == reflect.Struct:
+		block := GetHintMapStr(hints, key, "")
+		if block == nil {
+			return nil
+		}
+		d.decodeStruct(block, key, "", v)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		configs := GetHintsAsList(hints, key)
+		slice := reflect.MakeSlice(v.Type(), len(configs), len(configs))
+		for i, config := range configs {
+			d.decodeStruct(config, key, fmt.Sprintf("[%d]", i), slice.Index(i))
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("cannot decode hints into %s", v.Type())
+	}
+
+	if len(d.errors) > 0 {
+		return d.errors
+	}
+	return nil
+}
+
+type hintsDecoder struct {
+	errors HintDecodeErrors
+}
+
+func (d *hintsDecoder) fail(hint, field string, value any, err error) {
+	d.errors = append(d.errors, HintFieldError{Hint: hint, Field: field, Value: value, Err: err})
+}
+
+// decodeStruct decodes the hints of block, found at hintPath, into the struct v found at fieldPath
+func (d *hintsDecoder) decodeStruct(block mapstr.M, hintPath, fieldPath string, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("hint")
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			continue
+		}
+
+		value, err := block.GetValue(name)
+		if err != nil {
+			continue
+		}
+
+		fieldName := field.Name
+		if fieldPath != "" {
+			fieldName = fieldPath + "." + field.Name
+		}
+		d.decodeValue(value, hintPath+"."+name, fieldName, options == "json", v.Field(i))
+	}
+}
+
+// decodeValue decodes a hint value into v
+func (d *hintsDecoder) decodeValue(value any, hint, field string, asJSON bool, v reflect.Value) {
+	if v.Kind() == reflect.Pointer {
+		target := reflect.New(v.Type().Elem())
+		errorsBefore := len(d.errors)
+		d.decodeValue(value, hint, field, asJSON, target.Elem())
+		if len(d.errors) == errorsBefore {
+			v.Set(target)
+		}
+		return
+	}
+
+	str, isString := value.(string)
+	nested, isNested := value.(mapstr.M)
+
+	if asJSON {
+		if !isString {
+			d.fail(hint, field, value, errors.New("expected a JSON value"))
+			return
+		}
+		target := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(str), target.Interface()); err != nil {
+			d.fail(hint, field, value, err)
+			return
+		}
+		v.Set(target.Elem())
+		return
+	}
+
+	switch {
+	case v.Type() == mapStrType || v.Kind() == reflect.Map:
+		d.decodeMap(value, hint, field, v)
+		return
+	case v.Kind() == reflect.Struct:
+		if !isNested {
+			d.fail(hint, field, value, errors.New("expected nested hints"))
+			return
+		}
+		d.decodeStruct(nested, hint, field, v)
+		return
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		if !isNested {
+			d.fail(hint, field, value, errors.New("expected enumerated hints"))
+			return
+		}
+		d.decodeEnumerated(nested, hint, field, v)
+		return
+	}
+
+	if !isString {
+		d.fail(hint, field, value, fmt.Errorf("expected a value, got nested hints"))
+		return
+	}
+	if err := setScalar(str, v); err != nil {
+		d.fail(hint, field, value, err)
+	}
+}
+
+// decodeEnumerated decodes the enumerated blocks of raw into a slice of structs, in the order of GetConfigs.
+// Hints that are not in an enumerated block are decoded into an additional struct, at the end.
+func (d *hintsDecoder) decodeEnumerated(raw mapstr.M, hint, field string, v reflect.Value) {
+	enumerated, words := enumeratedBlocks(raw)
+
+	var blocks []mapstr.M
+	var paths []string
+	for _, block := range enumerated {
+		blocks = append(blocks, block.config)
+		paths = append(paths, hint+"."+block.key)
+	}
+	if len(words) > 0 {
+		defaultBlock := mapstr.M{}
+		for _, word := range words {
+			defaultBlock[word] = raw[word]
+		}
+		blocks = append(blocks, defaultBlock)
+		paths = append(paths, hint)
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(blocks), len(blocks))
+	for i, block := range blocks {
+		d.decodeStruct(block, paths[i], fmt.Sprintf("%s[%d]", field, i), slice.Index(i))
+	}
+	v.Set(slice)
+}
+
+// decodeMap decodes nested hints, or a JSON object, into a map
+func (d *hintsDecoder) decodeMap(value any, hint, field string, v reflect.Value) {
+	var data []byte
+	switch value := value.(type) {
+	case string:
+		data = []byte(value)
+	case mapstr.M:
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			d.fail(hint, field, value, err)
+			return
+		}
+	}
+
+	target := reflect.New(v.Type())
+	if err := json.Unmarshal(data, target.Interface()); err != nil {
+		d.fail(hint, field, value, err)
+		return
+	}
+	v.Set(target.Elem())
+}
+
+// setScalar parses a hint value into a field of a basic type
+func setScalar(str string, v reflect.Value) error {
+	if v.Type() == durationType {
+		duration, err := time.ParseDuration(str)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(duration))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(str)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(str, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(str, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		list := getStringAsList(str)
+		slice := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i, item := range list {
+			slice.Index(i).SetString(item)
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
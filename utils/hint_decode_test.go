@@ -0,0 +1,168 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+type testMultiline struct {
+	Pattern string `hint:"pattern"`
+	Negate  bool   `hint:"negate"`
+}
+
+type testProcessor struct {
+	AddFields mapstr.M `hint:"add_fields"`
+	DropEvent mapstr.M `hint:"drop_event"`
+}
+
+type testMetricsHints struct {
+	Enabled    *bool           `hint:"enabled"`
+	Module     string          `hint:"module"`
+	Period     time.Duration   `hint:"period"`
+	Timeout    time.Duration   `hint:"timeout"`
+	Retries    int             `hint:"retries"`
+	Ratio      float64         `hint:"ratio"`
+	Hosts      []string        `hint:"hosts"`
+	Multiline  testMultiline   `hint:"multiline"`
+	Processors []testProcessor `hint:"processors"`
+	Raw        []mapstr.M      `hint:"raw,json"`
+	Ignored    string
+}
+
+func TestDecodeHints(t *testing.T) {
+	hints := mapstr.M{
+		"metrics": mapstr.M{
+			"enabled": "true",
+			"module":  "prometheus",
+			"period":  "15s",
+			"retries": "3",
+			"ratio":   "0.5",
+			"hosts":   "a:9090, b:9090",
+			"multiline": mapstr.M{
+				"pattern": "^[",
+				"negate":  "true",
+			},
+			"processors": mapstr.M{
+				"1": mapstr.M{"add_fields": `{"fields": {"foo": "bar"}}`},
+				"2": mapstr.M{"drop_event": mapstr.M{"when": "x"}},
+			},
+			"raw": `[{"module": "prometheus"}]`,
+		},
+	}
+
+	out := testMetricsHints{Timeout: 5 * time.Second}
+	require.NoError(t, DecodeHints(hints, "metrics", &out))
+
+	enabled := true
+	assert.Equal(t, testMetricsHints{
+		Enabled:   &enabled,
+		Module:    "prometheus",
+		Period:    15 * time.Second,
+		Timeout:   5 * time.Second,
+		Retries:   3,
+		Ratio:     0.5,
+		Hosts:     []string{"a:9090", "b:9090"},
+		Multiline: testMultiline{Pattern: "^[", Negate: true},
+		Processors: []testProcessor{
+			{AddFields: mapstr.M{"fields": map[string]any{"foo": "bar"}}},
+			{DropEvent: mapstr.M{"when": "x"}},
+		},
+		Raw: []mapstr.M{{"module": "prometheus"}},
+	}, out)
+}
+
+func TestDecodeHints_EnumeratedBlocks(t *testing.T) {
+	hints := mapstr.M{
+		"metrics": mapstr.M{
+			"1": mapstr.M{
+				"module": "prometheus",
+				"period": "15s",
+			},
+			"2": mapstr.M{
+				"module": "dropwizard",
+				"period": "20s",
+			},
+			"module": "default",
+		},
+	}
+
+	var out []testMetricsHints
+	require.NoError(t, DecodeHints(hints, "metrics", &out))
+	assert.Equal(t, []testMetricsHints{
+		{Module: "prometheus", Period: 15 * time.Second},
+		{Module: "dropwizard", Period: 20 * time.Second},
+		{Module: "default"},
+	}, out)
+}
+
+func TestDecodeHints_Errors(t *testing.T) {
+	hints := mapstr.M{
+		"metrics": mapstr.M{
+			"module":    "prometheus",
+			"period":    "15",
+			"retries":   "many",
+			"multiline": "^[",
+			"processors": mapstr.M{
+				"1": mapstr.M{"add_fields": `{`},
+			},
+			"raw": `{"module": "prometheus"}`,
+		},
+	}
+
+	var out testMetricsHints
+	err := DecodeHints(hints, "metrics", &out)
+	require.Error(t, err)
+
+	var decodeErrors HintDecodeErrors
+	require.True(t, errors.As(err, &decodeErrors))
+
+	failed := map[string]string{}
+	for _, fieldError := range decodeErrors {
+		failed[fieldError.Hint] = fieldError.Field
+	}
+	assert.Equal(t, map[string]string{
+		"metrics.period":                  "Period",
+		"metrics.retries":                 "Retries",
+		"metrics.multiline":               "Multiline",
+		"metrics.processors.1.add_fields": "Processors[0].AddFields",
+		"metrics.raw":                     "Raw",
+	}, failed)
+	assert.Equal(t, "prometheus", out.Module, "valid hints should be decoded")
+}
+
+func TestDecodeHints_InvalidOutput(t *testing.T) {
+	hints := mapstr.M{"metrics": mapstr.M{"module": "prometheus"}}
+
+	var out testMetricsHints
+	assert.Error(t, DecodeHints(hints, "metrics", out))
+	assert.Error(t, DecodeHints(hints, "metrics", (*testMetricsHints)(nil)))
+
+	var str string
+	assert.Error(t, DecodeHints(hints, "metrics", &str))
+
+	assert.NoError(t, DecodeHints(hints, "logs", &out))
+	assert.Equal(t, testMetricsHints{}, out)
+}
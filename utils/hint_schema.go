@@ -0,0 +1,486 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// HintType is the type of the value of a hint
+type HintType int
+
+const (
+	// HintTypeString accepts any value
+	HintTypeString HintType = iota
+	// HintTypeBool accepts the values accepted by strconv.ParseBool
+	HintTypeBool
+	// HintTypeInt accepts integers
+	HintTypeInt
+	// HintTypeDuration accepts the values accepted by time.ParseDuration
+	HintTypeDuration
+	// HintTypeList accepts comma separated lists, restricted to Values when set
+	HintTypeList
+	// HintTypeJSON accepts JSON documents
+	HintTypeJSON
+	// HintTypeEnum accepts one of Values
+	HintTypeEnum
+	// HintTypeObject is a hint configured by nested hints, like multiline.pattern, whose values are not checked
+	HintTypeObject
+)
+
+// String returns the name of the type
+func (t HintType) String() string {
+	switch t {
+	case HintTypeString:
+		return "string"
+	case HintTypeBool:
+		return "bool"
+	case HintTypeInt:
+		return "int"
+	case HintTypeDuration:
+		return "duration"
+	case HintTypeList:
+		return "list"
+	case HintTypeJSON:
+		return "JSON"
+	case HintTypeEnum:
+		return "enum"
+	case HintTypeObject:
+		return "object"
+	}
+	return "unknown"
+}
+
+// HintSpec declares a hint
+type HintSpec struct {
+	// Name of the hint, nested hints are separated by dots, like multiline.pattern
+	Name string
+	Type HintType
+	// Values are the values accepted by enum hints and by the items of list hints
+	Values []string
+	// Required hints must be set in the default block and in every enumerated block that has hints
+	Required bool
+	// Default is the value set by ApplyDefaults when the hint is not set
+	Default string
+	// Deprecated hints are accepted with a warning, Replacement is the hint that should be used instead
+	Deprecated  bool
+	Replacement string
+	// Groups marks list hints whose values name groups of hints configured with <group>.<hint>
+	// hints, like the data_streams and metricsets hints
+	Groups bool
+}
+
+// HintSchema is a registry of the hints supported under a hints key, like co.elastic.metrics
+type HintSchema struct {
+	key   string
+	specs map[string]HintSpec
+}
+
+// HintError is an invalid hint found by HintSchema.Validate
+type HintError struct {
+	// Annotation is the key of the annotation defining the hint, like co.elastic.metrics/period
+	Annotation string
+	// Container is the container the hint is defined for, it is empty for hints of the pod
+	Container string
+	// Hint is the name of the hint
+	Hint  string
+	Value string
+	// Reason describes why the hint is invalid
+	Reason string
+	// Suggestion describes how to fix the hint, it can be empty
+	Suggestion string
+	// Warning is true for hints that are still used, like deprecated hints
+	Warning bool
+}
+
+// Error returns a message describing the invalid hint
+func (e HintError) Error() string {
+	var msg strings.Builder
+	msg.WriteString("hint ")
+	msg.WriteString(e.Annotation)
+	if e.Container != "" {
+		fmt.Fprintf(&msg, " of container %s", e.Container)
+	}
+	msg.WriteString(": ")
+	msg.WriteString(e.Reason)
+	if e.Suggestion != "" {
+		msg.WriteString(", ")
+		msg.WriteString(e.Suggestion)
+	}
+	return msg.String()
+}
+
+// NewHintSchema creates a schema for the hints of the given key, like metrics or logs
+func NewHintSchema(key string, specs ...HintSpec) (*HintSchema, error) {
+	s := &HintSchema{
+		key:   key,
+		specs: map[string]HintSpec{},
+	}
+	for _, spec := range specs {
+		if err := s.Register(spec); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Register adds a hint to the schema
+func (s *HintSchema) Register(spec HintSpec) error {
+	if spec.Name == "" {
+		return errors.New("hint without name")
+	}
+	if _, ok := s.specs[spec.Name]; ok {
+		return fmt.Errorf("hint %s is already registered", spec.Name)
+	}
+	if spec.Type == HintTypeEnum && len(spec.Values) == 0 {
+		return fmt.Errorf("enum hint %s without values", spec.Name)
+	}
+	if spec.Groups && spec.Type != HintTypeList {
+		return fmt.Errorf("hint %s declaring groups must be a list", spec.Name)
+	}
+	if spec.Default != "" {
+		if reason, _ := spec.check(spec.Default); reason != "" {
+			return fmt.Errorf("invalid default of hint %s: %s", spec.Name, reason)
+		}
+	}
+	s.specs[spec.Name] = spec
+	return nil
+}
+
+// Lookup returns the spec of a hint
+func (s *HintSchema) Lookup(name string) (HintSpec, bool) {
+	spec, ok := s.specs[name]
+	return spec, ok
+}
+
+// Names returns the sorted names of the hints of the schema, they can be used as the supported hints of GenerateHints
+func (s *HintSchema) Names() []string {
+	names := make([]string, 0, len(s.specs))
+	for name := range s.specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyDefaults sets the default value of the hints missing in the default block of the hints generated by
+// GenerateHints. Hints are only set if there are hints for the key of the schema.
+func (s *HintSchema) ApplyDefaults(hints mapstr.M) {
+	block := GetHintMapStr(hints, s.key, "")
+	if block == nil {
+		return
+	}
+	for _, name := range s.Names() {
+		spec := s.specs[name]
+		if spec.Default == "" {
+			continue
+		}
+		if _, err := block.GetValue(name); err != nil {
+			_, _ = block.Put(name, spec.Default)
+		}
+	}
+}
+
+// hintEntry is a hint defined by an annotation
+type hintEntry struct {
+	annotation string
+	container  string
+	path       string
+	value      any
+}
+
+// Validate checks the hints of the schema key defined in annotations under prefix, for the given container,
+// as they would be parsed by GenerateHints. The returned errors are sorted by annotation.
+func (s *HintSchema) Validate(annotations mapstr.M, container, prefix string) []HintError {
+	rawEntries, err := annotations.GetValue(prefix)
+	if err != nil {
+		return nil
+	}
+	entries, ok := rawEntries.(mapstr.M)
+	if !ok {
+		return nil
+	}
+
+	var hintEntries []hintEntry
+	for key, rawValue := range entries {
+		if hintPath, found := strings.CutPrefix(key, s.key+"/"); found {
+			hintEntries = appendHintEntries(hintEntries, prefix+"."+key, "", hintPath, rawValue)
+			continue
+		}
+		containerHints, ok := rawValue.(mapstr.M)
+		if key != s.key || container == "" || !ok {
+			continue
+		}
+		for containerKey, rawVal := range containerHints {
+			if hintPath, found := strings.CutPrefix(containerKey, container+"/"); found {
+				hintEntries = appendHintEntries(hintEntries, prefix+"."+key+"."+containerKey, container, hintPath, rawVal)
+			}
+		}
+	}
+
+	groups := s.groups(hintEntries)
+	blocks := map[string]map[string]bool{}
+	var hintErrors []HintError
+	for _, entry := range hintEntries {
+		block, name := splitHintBlock(entry.path, groups)
+		if _, isGroup := groups[block]; !isGroup {
+			if blocks[block] == nil {
+				blocks[block] = map[string]bool{}
+			}
+			blocks[block][name] = true
+		}
+		if hintError, invalid := s.validateEntry(entry, block, name); invalid {
+			hintErrors = append(hintErrors, hintError)
+		}
+	}
+
+	for block, names := range blocks {
+		hintErrors = append(hintErrors, s.checkRequired(block, names, prefix)...)
+	}
+
+	sort.Slice(hintErrors, func(i, j int) bool {
+		if hintErrors[i].Annotation != hintErrors[j].Annotation {
+			return hintErrors[i].Annotation < hintErrors[j].Annotation
+		}
+		return hintErrors[i].Reason < hintErrors[j].Reason
+	})
+	return hintErrors
+}
+
+// appendHintEntries appends an entry for each value of the hint at path, values of nested hints are
+// maps whose keys are the following parts of the path
+func appendHintEntries(entries []hintEntry, annotation, container, path string, value any) []hintEntry {
+	nested, ok := value.(mapstr.M)
+	if !ok {
+		return append(entries, hintEntry{annotation: annotation, container: container, path: path, value: value})
+	}
+	for key, nestedValue := range nested {
+		entries = appendHintEntries(entries, annotation+"."+key, container, path+"."+key, nestedValue)
+	}
+	return entries
+}
+
+// groups returns the groups declared by the values of the hints marked as Groups
+func (s *HintSchema) groups(entries []hintEntry) map[string]struct{} {
+	groups := map[string]struct{}{}
+	for _, entry := range entries {
+		spec, ok := s.specs[entry.path]
+		if !ok || !spec.Groups {
+			continue
+		}
+		value, _ := entry.value.(string)
+		for _, group := range getStringAsList(value) {
+			groups[group] = struct{}{}
+		}
+	}
+	return groups
+}
+
+// splitHintBlock returns the block of a hint path, that is the enumeration or group it belongs to
+// or empty for the default block, and the name of the hint in the block
+func splitHintBlock(path string, groups map[string]struct{}) (string, string) {
+	first, rest, found := strings.Cut(path, ".")
+	if !found {
+		return "", path
+	}
+	if _, err := strconv.Atoi(first); err == nil {
+		return first, rest
+	}
+	if _, ok := groups[first]; ok {
+		return first, rest
+	}
+	return "", path
+}
+
+// resolve returns the spec of a hint name, that can be nested under an object hint
+func (s *HintSchema) resolve(name string) (HintSpec, bool) {
+	for candidate := name; ; {
+		if spec, ok := s.specs[candidate]; ok {
+			if candidate == name || spec.Type == HintTypeObject {
+				return spec, true
+			}
+			return HintSpec{}, false
+		}
+		i := strings.LastIndex(candidate, ".")
+		if i < 0 {
+			return HintSpec{}, false
+		}
+		candidate = candidate[:i]
+	}
+}
+
+// priorityHintSpec is the spec of the priority hint, reserved in enumerated blocks to order them
+var priorityHintSpec = HintSpec{Name: HintPriorityKey, Type: HintTypeInt}
+
+func (s *HintSchema) validateEntry(entry hintEntry, block, name string) (HintError, bool) {
+	value, _ := entry.value.(string)
+	hintError := HintError{
+		Annotation: entry.annotation,
+		Container:  entry.container,
+		Hint:       name,
+		Value:      value,
+	}
+
+	spec, ok := s.resolve(name)
+	if _, err := strconv.Atoi(block); err == nil && name == HintPriorityKey {
+		spec, ok = priorityHintSpec, true
+	}
+	if !ok {
+		hintError.Reason = "unsupported hint"
+		if suggestion := closest(name, s.Names()); suggestion != "" {
+			hintError.Suggestion = fmt.Sprintf("did you mean %s?", suggestion)
+		}
+		return hintError, true
+	}
+	if spec.Name != name {
+		// Nested hint of an object hint
+		return hintError, false
+	}
+
+	if reason, suggestion := spec.check(value); reason != "" {
+		hintError.Reason = reason
+		hintError.Suggestion = suggestion
+		return hintError, true
+	}
+
+	if spec.Deprecated {
+		hintError.Reason = "deprecated hint"
+		if spec.Replacement != "" {
+			hintError.Suggestion = fmt.Sprintf("use %s instead", spec.Replacement)
+		}
+		hintError.Warning = true
+		return hintError, true
+	}
+	return hintError, false
+}
+
+// check returns why the value is not valid for the hint and a suggestion to fix it, or empty strings
+// if it is valid
+func (spec HintSpec) check(value string) (string, string) {
+	switch spec.Type {
+	case HintTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Sprintf("invalid bool %q", value), "use true or false"
+		}
+	case HintTypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Sprintf("invalid int %q", value), ""
+		}
+	case HintTypeDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			suggestion := "use a duration like 10s or 1m"
+			if _, err := strconv.Atoi(value); err == nil {
+				suggestion = fmt.Sprintf("add a unit, like %ss", value)
+			}
+			return fmt.Sprintf("invalid duration %q", value), suggestion
+		}
+	case HintTypeList:
+		if len(spec.Values) == 0 {
+			break
+		}
+		for _, item := range getStringAsList(value) {
+			if !slices.Contains(spec.Values, item) {
+				return fmt.Sprintf("invalid list item %q", item), valuesSuggestion(item, spec.Values)
+			}
+		}
+	case HintTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return "invalid JSON", ""
+		}
+	case HintTypeEnum:
+		if !slices.Contains(spec.Values, value) {
+			return fmt.Sprintf("invalid value %q", value), valuesSuggestion(value, spec.Values)
+		}
+	}
+	return "", ""
+}
+
+// checkRequired returns an error for each required hint missing in a block, they are reported as missing
+// annotations of the pod
+func (s *HintSchema) checkRequired(block string, names map[string]bool, prefix string) []HintError {
+	var hintErrors []HintError
+	for _, name := range s.Names() {
+		spec := s.specs[name]
+		if !spec.Required || names[name] {
+			continue
+		}
+		hint := name
+		if block != "" {
+			hint = block + "." + name
+		}
+		hintErrors = append(hintErrors, HintError{
+			Annotation: prefix + "." + s.key + "/" + hint,
+			Hint:       name,
+			Reason:     "missing required hint",
+		})
+	}
+	return hintErrors
+}
+
+func valuesSuggestion(value string, values []string) string {
+	if suggestion := closest(value, values); suggestion != "" {
+		return fmt.Sprintf("did you mean %s?", suggestion)
+	}
+	return "use one of " + strings.Join(values, ", ")
+}
+
+// closest returns the candidate closest to s, or empty if none is close enough to be a likely typo
+func closest(s string, candidates []string) string {
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		distance := levenshtein(s, candidate)
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if bestDistance < 0 || bestDistance > max(2, len(s)/3) {
+		return ""
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,199 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func newTestMetricsSchema(t *testing.T) *HintSchema {
+	schema, err := NewHintSchema("metrics",
+		HintSpec{Name: "enabled", Type: HintTypeBool},
+		HintSpec{Name: "module", Type: HintTypeString, Required: true},
+		HintSpec{Name: "metricsets", Type: HintTypeList, Groups: true},
+		HintSpec{Name: "period", Type: HintTypeDuration, Default: "10s"},
+		HintSpec{Name: "timeout", Type: HintTypeDuration},
+		HintSpec{Name: "retries", Type: HintTypeInt},
+		HintSpec{Name: "ssl.verification_mode", Type: HintTypeEnum, Values: []string{"full", "strict", "certificate", "none"}},
+		HintSpec{Name: "processors", Type: HintTypeObject},
+		HintSpec{Name: "raw", Type: HintTypeJSON},
+		HintSpec{Name: "namespace", Type: HintTypeString, Deprecated: true, Replacement: "data_stream.namespace"},
+	)
+	require.NoError(t, err)
+	return schema
+}
+
+func annotationsMap(annotations map[string]string) mapstr.M {
+	annMap := mapstr.M{}
+	for k, v := range annotations {
+		_, _ = annMap.Put(k, v)
+	}
+	return annMap
+}
+
+func TestNewHintSchema(t *testing.T) {
+	_, err := NewHintSchema("metrics", HintSpec{Name: "module"}, HintSpec{Name: "module"})
+	assert.Error(t, err, "duplicated hints should be rejected")
+
+	_, err = NewHintSchema("metrics", HintSpec{Name: "mode", Type: HintTypeEnum})
+	assert.Error(t, err, "enums without values should be rejected")
+
+	_, err = NewHintSchema("metrics", HintSpec{Name: "period", Type: HintTypeDuration, Default: "10"})
+	assert.Error(t, err, "invalid defaults should be rejected")
+
+	_, err = NewHintSchema("metrics", HintSpec{Name: "metricsets", Type: HintTypeString, Groups: true})
+	assert.Error(t, err, "groups should be lists")
+
+	schema := newTestMetricsSchema(t)
+	assert.Contains(t, schema.Names(), "ssl.verification_mode")
+	spec, ok := schema.Lookup("period")
+	assert.True(t, ok)
+	assert.Equal(t, HintTypeDuration, spec.Type)
+}
+
+func TestHintSchema_Validate(t *testing.T) {
+	schema := newTestMetricsSchema(t)
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    []HintError
+	}{
+		{
+			name: "valid hints",
+			annotations: map[string]string{
+				"co.elastic.metrics/module":                     "prometheus",
+				"co.elastic.metrics/period":                     "10s",
+				"co.elastic.metrics/ssl.verification_mode":      "none",
+				"co.elastic.metrics/processors.add_fields":      `{"fields": {}}`,
+				"co.elastic.metrics/raw":                        `{"module": "prometheus"}`,
+				"co.elastic.metrics.nginx/timeout":              "5s",
+				"co.elastic.metrics.other/anything":             "ignored",
+				"co.elastic.logs/whatever":                      "ignored",
+				"co.elastic.metrics/enabled":                    "true",
+				"co.elastic.metrics/retries":                    "3",
+				"co.elastic.metrics/processors.1.drop_event.if": "x",
+			},
+		},
+		{
+			name: "invalid values",
+			annotations: map[string]string{
+				"co.elastic.metrics/module":                "prometheus",
+				"co.elastic.metrics/period":                "10",
+				"co.elastic.metrics/enabled":               "yes",
+				"co.elastic.metrics/ssl.verification_mode": "ful",
+				"co.elastic.metrics/raw":                   "{",
+				"co.elastic.metrics.nginx/retries":         "many",
+			},
+			expected: []HintError{
+				{Annotation: "co.elastic.metrics.nginx/retries", Container: "nginx", Hint: "retries", Value: "many", Reason: `invalid int "many"`},
+				{Annotation: "co.elastic.metrics/enabled", Hint: "enabled", Value: "yes", Reason: `invalid bool "yes"`, Suggestion: "use true or false"},
+				{Annotation: "co.elastic.metrics/period", Hint: "period", Value: "10", Reason: `invalid duration "10"`, Suggestion: "add a unit, like 10s"},
+				{Annotation: "co.elastic.metrics/raw", Hint: "raw", Value: "{", Reason: "invalid JSON"},
+				{Annotation: "co.elastic.metrics/ssl.verification_mode", Hint: "ssl.verification_mode", Value: "ful", Reason: `invalid value "ful"`, Suggestion: "did you mean full?"},
+			},
+		},
+		{
+			name: "unsupported and deprecated hints",
+			annotations: map[string]string{
+				"co.elastic.metrics/module":    "prometheus",
+				"co.elastic.metrics/perod":     "10s",
+				"co.elastic.metrics/namespace": "prod",
+				"co.elastic.metrics/foo":       "bar",
+			},
+			expected: []HintError{
+				{Annotation: "co.elastic.metrics/foo", Hint: "foo", Value: "bar", Reason: "unsupported hint"},
+				{Annotation: "co.elastic.metrics/namespace", Hint: "namespace", Value: "prod", Reason: "deprecated hint", Suggestion: "use data_stream.namespace instead", Warning: true},
+				{Annotation: "co.elastic.metrics/perod", Hint: "perod", Value: "10s", Reason: "unsupported hint", Suggestion: "did you mean period?"},
+			},
+		},
+		{
+			name: "enumerated blocks and groups",
+			annotations: map[string]string{
+				"co.elastic.metrics/1.module":       "prometheus",
+				"co.elastic.metrics/1.period":       "15s",
+				"co.elastic.metrics/2.period":       "15s",
+				"co.elastic.metrics/metricsets":     "istiod,proxy",
+				"co.elastic.metrics/istiod.period":  "5m",
+				"co.elastic.metrics/proxy.timeoutt": "5m",
+			},
+			expected: []HintError{
+				{Annotation: "co.elastic.metrics/2.module", Hint: "module", Reason: "missing required hint"},
+				{Annotation: "co.elastic.metrics/module", Hint: "module", Reason: "missing required hint"},
+				{Annotation: "co.elastic.metrics/proxy.timeoutt", Hint: "timeoutt", Value: "5m", Reason: "unsupported hint", Suggestion: "did you mean timeout?"},
+			},
+		},
+		{
+			name: "priority of enumerated blocks",
+			annotations: map[string]string{
+				"co.elastic.metrics/module":           "prometheus",
+				"co.elastic.metrics/1.module":         "prometheus",
+				"co.elastic.metrics/1.priority":       "-1",
+				"co.elastic.metrics/2.module":         "prometheus",
+				"co.elastic.metrics/2.priority":       "high",
+				"co.elastic.metrics.nginx/3.module":   "prometheus",
+				"co.elastic.metrics.nginx/3.priority": "10",
+				"co.elastic.metrics/priority":         "1",
+				"co.elastic.metrics/metricsets":       "proxy",
+				"co.elastic.metrics/proxy.priority":   "1",
+			},
+			expected: []HintError{
+				{Annotation: "co.elastic.metrics/2.priority", Hint: "priority", Value: "high", Reason: `invalid int "high"`},
+				{Annotation: "co.elastic.metrics/priority", Hint: "priority", Value: "1", Reason: "unsupported hint"},
+				{Annotation: "co.elastic.metrics/proxy.priority", Hint: "priority", Value: "1", Reason: "unsupported hint"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, schema.Validate(annotationsMap(test.annotations), "nginx", "co.elastic"))
+		})
+	}
+}
+
+func TestHintError_Error(t *testing.T) {
+	err := HintError{
+		Annotation: "co.elastic.metrics.nginx/period",
+		Container:  "nginx",
+		Reason:     `invalid duration "10"`,
+		Suggestion: "add a unit, like 10s",
+	}
+	assert.Equal(t, `hint co.elastic.metrics.nginx/period of container nginx: invalid duration "10", add a unit, like 10s`, err.Error())
+}
+
+func TestHintSchema_ApplyDefaults(t *testing.T) {
+	schema := newTestMetricsSchema(t)
+
+	hints := mapstr.M{"metrics": mapstr.M{"module": "prometheus"}}
+	schema.ApplyDefaults(hints)
+	assert.Equal(t, mapstr.M{"metrics": mapstr.M{"module": "prometheus", "period": "10s"}}, hints)
+
+	hints = mapstr.M{"metrics": mapstr.M{"module": "prometheus", "period": "1m"}}
+	schema.ApplyDefaults(hints)
+	assert.Equal(t, mapstr.M{"metrics": mapstr.M{"module": "prometheus", "period": "1m"}}, hints)
+
+	hints = mapstr.M{"logs": mapstr.M{"enabled": "true"}}
+	schema.ApplyDefaults(hints)
+	assert.Equal(t, mapstr.M{"logs": mapstr.M{"enabled": "true"}}, hints)
+}
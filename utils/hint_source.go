@@ -0,0 +1,151 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"sort"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/safemapstr"
+)
+
+// HintLevel is the level of the object hints are defined in, hints of higher levels take precedence
+type HintLevel int
+
+const (
+	// HintLevelNamespace is the level of hints defined for all the workloads of a namespace,
+	// like the annotations of a Kubernetes namespace
+	HintLevelNamespace HintLevel = iota
+	// HintLevelPod is the level of hints defined for a group of containers, like the annotations of a Kubernetes pod,
+	// a Nomad task group or an ECS task
+	HintLevelPod
+	// HintLevelContainer is the level of hints defined for a single container, like the labels of a Docker container
+	HintLevelContainer
+)
+
+// HintSource provides the hints defined in an object, like the annotations of a Kubernetes pod
+// or the labels of a Docker container
+type HintSource interface {
+	// Level returns the level of the hints of the source
+	Level() HintLevel
+	// Hints returns the hints under prefix, like co.elastic, normalized as returned by GenerateHints:
+	// the hints of each type are under its name, like logs or metrics
+	Hints(prefix string) mapstr.M
+}
+
+// GenerateHintsFromSources returns the hints of all the sources under prefix, merged hint by hint.
+// Hints of containers take precedence over hints of pods, and hints of pods over hints of namespaces,
+// so GenerateHintsFromSources(prefix, container, pod, namespace) returns the hints of the namespace,
+// overridden by the hints of the pod, overridden by the hints of the container.
+// Hints of sources of the same level are overridden by the hints of the sources after them.
+func GenerateHintsFromSources(prefix string, sources ...HintSource) mapstr.M {
+	sorted := make([]HintSource, 0, len(sources))
+	for _, source := range sources {
+		if source != nil {
+			sorted = append(sorted, source)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Level() < sorted[j].Level()
+	})
+
+	hints := mapstr.M{}
+	for _, source := range sorted {
+		// Hints can hold the maps of the source, they are copied so merging doesn't modify them
+		hints.DeepUpdate(source.Hints(prefix).Clone())
+	}
+	return hints
+}
+
+type kubernetesAnnotationsHintSource struct {
+	level       HintLevel
+	annotations mapstr.M
+	container   string
+}
+
+// NewKubernetesPodHintSource returns a source for the hints in the annotations of a pod, like the annotations in
+// the kubernetes metadata of events. Hints defined for the given container, like co.elastic.logs.<container>/json,
+// take precedence over the hints defined for all the containers of the pod, as in GenerateHints.
+func NewKubernetesPodHintSource(annotations mapstr.M, container string) HintSource {
+	return &kubernetesAnnotationsHintSource{
+		level:       HintLevelPod,
+		annotations: annotations,
+		container:   container,
+	}
+}
+
+// NewKubernetesNamespaceHintSource returns a source for the hints in the annotations of a namespace
+func NewKubernetesNamespaceHintSource(annotations mapstr.M) HintSource {
+	return &kubernetesAnnotationsHintSource{
+		level:       HintLevelNamespace,
+		annotations: annotations,
+	}
+}
+
+// Level returns the level of the hints of the source
+func (s *kubernetesAnnotationsHintSource) Level() HintLevel {
+	return s.level
+}
+
+// Hints returns the hints under prefix
+func (s *kubernetesAnnotationsHintSource) Hints(prefix string) mapstr.M {
+	if s.annotations == nil {
+		return mapstr.M{}
+	}
+	hints, _ := GenerateHints(s.annotations, s.container, prefix, false, nil)
+	return hints
+}
+
+type metadataHintSource struct {
+	level     HintLevel
+	metadata  mapstr.M
+	container string
+}
+
+// NewDockerLabelsHintSource returns a source for the hints in the labels of a Docker container. Labels must not be
+// dedotted, they have the same format as Kubernetes annotations, like co.elastic.logs/multiline.pattern.
+func NewDockerLabelsHintSource(labels map[string]string) HintSource {
+	return NewMetadataHintSource(HintLevelContainer, labels, "")
+}
+
+// NewMetadataHintSource returns a source for the hints in flat key-value metadata of the given level, like the meta
+// of Nomad jobs, groups and tasks, or the Docker labels of ECS task definitions. Keys have the same format as
+// Kubernetes annotations, so metadata can define hints for the given container, like co.elastic.logs.<container>/json,
+// that take precedence over the rest of hints of the metadata.
+func NewMetadataHintSource(level HintLevel, metadata map[string]string, container string) HintSource {
+	m := mapstr.M{}
+	for k, v := range metadata {
+		_ = safemapstr.Put(m, k, v)
+	}
+	return &metadataHintSource{
+		level:     level,
+		metadata:  m,
+		container: container,
+	}
+}
+
+// Level returns the level of the hints of the source
+func (s *metadataHintSource) Level() HintLevel {
+	return s.level
+}
+
+// Hints returns the hints under prefix
+func (s *metadataHintSource) Hints(prefix string) mapstr.M {
+	hints, _ := GenerateHints(s.metadata, s.container, prefix, false, nil)
+	return hints
+}
@@ -0,0 +1,92 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestGenerateHintsFromSources(t *testing.T) {
+	namespace := NewKubernetesNamespaceHintSource(annotationsMap(map[string]string{
+		"co.elastic.logs/multiline.pattern": "^namespace",
+		"co.elastic.logs/json.add_error":    "true",
+		"co.elastic.metrics/period":         "1m",
+		"co.elastic.metrics.nginx/timeout":  "ignored for namespaces",
+	}))
+	pod := NewKubernetesPodHintSource(annotationsMap(map[string]string{
+		"co.elastic.logs/multiline.pattern": "^pod",
+		"co.elastic.metrics/module":         "prometheus",
+		"co.elastic.metrics/period":         "30s",
+		"co.elastic.metrics.nginx/period":   "15s",
+		"co.elastic.metrics.other/period":   "5s",
+	}), "nginx")
+	container := NewDockerLabelsHintSource(map[string]string{
+		"co.elastic.logs/multiline.pattern": "^container",
+		"com.example.other":                 "ignored",
+	})
+
+	expected := mapstr.M{
+		"logs": mapstr.M{
+			"multiline": mapstr.M{"pattern": "^container"},
+			"json":      mapstr.M{"add_error": "true"},
+		},
+		"metrics": mapstr.M{
+			"module": "prometheus",
+			"period": "15s",
+		},
+	}
+	assert.Equal(t, expected, GenerateHintsFromSources("co.elastic", container, pod, namespace))
+	assert.Equal(t, expected, GenerateHintsFromSources("co.elastic", namespace, nil, pod, container), "order of sources shouldn't matter")
+
+	assert.Equal(t, mapstr.M{
+		"logs": mapstr.M{
+			"multiline": mapstr.M{"pattern": "^namespace"},
+			"json":      mapstr.M{"add_error": "true"},
+		},
+		"metrics": mapstr.M{"period": "1m"},
+	}, namespace.Hints("co.elastic"), "sources should not be modified by merges")
+}
+
+func TestGenerateHintsFromSources_SameLevel(t *testing.T) {
+	group := NewMetadataHintSource(HintLevelPod, map[string]string{
+		"co.elastic.logs/enabled":                 "false",
+		"co.elastic.logs/processors.1.add_fields": `{"fields": {"group": "web"}}`,
+	}, "")
+	task := NewMetadataHintSource(HintLevelPod, map[string]string{
+		"co.elastic.logs/enabled":                  "true",
+		"co.elastic.logs.sidecar/json":             "ignored for other containers",
+		"co.elastic.logs.app/json.keys_under_root": "true",
+	}, "app")
+
+	assert.Equal(t, mapstr.M{
+		"logs": mapstr.M{
+			"enabled":    "true",
+			"processors": mapstr.M{"1": mapstr.M{"add_fields": `{"fields": {"group": "web"}}`}},
+			"json":       mapstr.M{"keys_under_root": "true"},
+		},
+	}, GenerateHintsFromSources("co.elastic", group, task))
+}
+
+func TestGenerateHintsFromSources_Empty(t *testing.T) {
+	assert.Equal(t, mapstr.M{}, GenerateHintsFromSources("co.elastic"))
+	assert.Equal(t, mapstr.M{}, GenerateHintsFromSources("co.elastic", NewKubernetesPodHintSource(nil, "nginx")))
+}
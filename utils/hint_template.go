@@ -0,0 +1,314 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// templateDataPrefix is the prefix of the references to the metadata of the event
+const templateDataPrefix = "data."
+
+// ResolveHintTemplate replaces the references in a hint value with values of the metadata of the event.
+//
+// References have the ${data.<field>} format, like ${data.kubernetes.pod.name}. They accept both the Elastic Agent
+// and the Beats syntaxes for the values used when a field is missing, so the same hints work in both:
+//   - Elastic Agent fallbacks, ${data.a|data.b|'constant'}: the first alternative that is found is used, constants
+//     are quoted with single or double quotes.
+//   - Beats defaults, ${data.a:constant}: the text after the colon is used when the field is missing, it can
+//     follow fallbacks, like ${data.a|data.b:constant}.
+//
+// Functions are called with parentheses, so they don't collide with those syntaxes, and their arguments can be
+// fields, constants or other functions:
+//   - default(value, fallback): uses fallback when value is missing or empty
+//   - lower(value): converts value to lower case
+//   - join(value, separator): joins list values with separator
+//
+// For example ${lower(data.kubernetes.labels.app)|'unknown'} or ${join(data.kubernetes.hosts, ';')}.
+// Double quoted constants follow the escaping rules of Go strings. Lists are joined with commas when they are not
+// joined explicitly, and $${ is replaced with a literal ${.
+// An error is returned if a referenced field is missing and has no fallback nor default.
+func ResolveHintTemplate(value string, data mapstr.M) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	var out strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			out.WriteString(value)
+			return out.String(), nil
+		}
+		if start > 0 && value[start-1] == '$' {
+			out.WriteString(value[:start-1])
+			out.WriteString("${")
+			value = value[start+2:]
+			continue
+		}
+		out.WriteString(value[:start])
+
+		end, err := findReferenceEnd(value, start+2)
+		if err != nil {
+			return "", err
+		}
+		resolved, err := resolveReference(value[start+2:end], data)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(resolved)
+		value = value[end+1:]
+	}
+}
+
+// ResolveHintsTemplates returns a copy of hints with the references in all the values resolved with ResolveHintTemplate.
+// It returns the errors of all the values that couldn't be resolved.
+func ResolveHintsTemplates(hints mapstr.M, data mapstr.M) (mapstr.M, error) {
+	var errs []error
+	out := resolveTemplates(hints, data, "", &errs)
+	return out, errors.Join(errs...)
+}
+
+func resolveTemplates(hints mapstr.M, data mapstr.M, path string, errs *[]error) mapstr.M {
+	out := make(mapstr.M, len(hints))
+	for key, value := range hints {
+		switch value := value.(type) {
+		case mapstr.M:
+			out[key] = resolveTemplates(value, data, path+key+".", errs)
+		case string:
+			resolved, err := ResolveHintTemplate(value, data)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("hint %s%s: %w", path, key, err))
+				resolved = value
+			}
+			out[key] = resolved
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// findReferenceEnd returns the position of the } closing the reference starting at start
+func findReferenceEnd(value string, start int) (int, error) {
+	end, err := indexTemplateExpr(value, start, "}")
+	if err != nil {
+		return 0, err
+	}
+	if end < 0 {
+		return 0, fmt.Errorf("unterminated reference in %q", value)
+	}
+	return end, nil
+}
+
+// resolveReference resolves the expression of a reference, that is its alternatives and its default
+func resolveReference(expr string, data mapstr.M) (string, error) {
+	alternatives, defaultValue, hasDefault := expr, "", false
+	colon, err := indexTemplateExpr(expr, 0, ":")
+	if err != nil {
+		return "", err
+	}
+	if colon >= 0 {
+		alternatives, defaultValue, hasDefault = expr[:colon], expr[colon+1:], true
+	}
+
+	terms, err := splitTemplateExpr(alternatives, '|')
+	if err != nil {
+		return "", err
+	}
+	for _, term := range terms {
+		value, found, err := evalTemplateTerm(term, data)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			return formatTemplateValue(value, ","), nil
+		}
+	}
+	if hasDefault {
+		return defaultValue, nil
+	}
+	return "", fmt.Errorf("missing value for %s", strings.TrimSpace(expr))
+}
+
+// evalTemplateTerm evaluates a field, a constant or a function call, it returns false if the value is missing
+func evalTemplateTerm(term string, data mapstr.M) (any, bool, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return nil, false, errors.New("empty expression")
+	}
+
+	if term[0] == '\'' || term[0] == '"' {
+		value, err := unquoteTemplateArg(term)
+		return value, err == nil, err
+	}
+
+	if name, rawArgs, found := strings.Cut(term, "("); found {
+		if !strings.HasSuffix(rawArgs, ")") {
+			return nil, false, fmt.Errorf("invalid function call %q", term)
+		}
+		args, err := splitTemplateExpr(strings.TrimSuffix(rawArgs, ")"), ',')
+		if err != nil {
+			return nil, false, err
+		}
+		return callTemplateFunction(strings.TrimSpace(name), args, data)
+	}
+
+	if !strings.HasPrefix(term, templateDataPrefix) {
+		return nil, false, fmt.Errorf("invalid reference %q, references must start with %s", term, templateDataPrefix)
+	}
+	if data == nil {
+		return nil, false, nil
+	}
+	value, err := data.GetValue(strings.TrimPrefix(term, templateDataPrefix))
+	if err != nil || value == nil {
+		return nil, false, nil
+	}
+	return value, true, nil
+}
+
+// callTemplateFunction calls the function name with args, the values of missing arguments are only used by default
+func callTemplateFunction(name string, args []string, data mapstr.M) (any, bool, error) {
+	arity := map[string]int{"default": 2, "lower": 1, "join": 2}
+	expected, ok := arity[name]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown function %q", name)
+	}
+	if len(args) != expected {
+		return nil, false, fmt.Errorf("%s expects %d arguments, got %d", name, expected, len(args))
+	}
+
+	value, found, err := evalTemplateTerm(args[0], data)
+	if err != nil {
+		return nil, false, err
+	}
+	switch name {
+	case "default":
+		if found && !isEmptyTemplateValue(value) {
+			return value, true, nil
+		}
+		return evalTemplateTerm(args[1], data)
+	case "lower":
+		if !found {
+			return nil, false, nil
+		}
+		return strings.ToLower(formatTemplateValue(value, ",")), true, nil
+	default: // join
+		separator, sepFound, err := evalTemplateTerm(args[1], data)
+		if err != nil {
+			return nil, false, err
+		}
+		if !found || !sepFound {
+			return nil, false, nil
+		}
+		return formatTemplateValue(value, formatTemplateValue(separator, ",")), true, nil
+	}
+}
+
+// indexTemplateExpr returns the index of the first of chars in expr after start that is outside of quoted strings
+// and parentheses, or -1 if there is none
+func indexTemplateExpr(expr string, start int, chars string) (int, error) {
+	var quote byte
+	depth := 0
+	for i := start; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case depth == 0 && strings.IndexByte(chars, c) >= 0:
+			return i, nil
+		}
+	}
+	if quote != 0 {
+		return 0, fmt.Errorf("unterminated string in %q", expr[start:])
+	}
+	return -1, nil
+}
+
+// splitTemplateExpr splits expr on sep outside of quoted strings and parentheses
+func splitTemplateExpr(expr string, sep byte) ([]string, error) {
+	var parts []string
+	for {
+		i, err := indexTemplateExpr(expr, 0, string(sep))
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 {
+			return append(parts, expr), nil
+		}
+		parts = append(parts, expr[:i])
+		expr = expr[i+1:]
+	}
+}
+
+// unquoteTemplateArg returns the value of a constant, double quoted constants follow the escaping rules
+// of Go strings and single quoted ones have no escaping
+func unquoteTemplateArg(arg string) (string, error) {
+	if len(arg) >= 2 && arg[0] == '\'' && arg[len(arg)-1] == '\'' {
+		return arg[1 : len(arg)-1], nil
+	}
+	unquoted, err := strconv.Unquote(arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s: %w", arg, err)
+	}
+	return unquoted, nil
+}
+
+func isEmptyTemplateValue(value any) bool {
+	if str, ok := value.(string); ok {
+		return str == ""
+	}
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Slice && v.Len() == 0
+}
+
+// formatTemplateValue returns the string representation of a value, lists are joined with separator
+func formatTemplateValue(value any, separator string) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case []string:
+		return strings.Join(value, separator)
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Slice {
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, fmt.Sprint(v.Index(i).Interface()))
+		}
+		return strings.Join(items, separator)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,132 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+func TestResolveHintTemplate(t *testing.T) {
+	data := mapstr.M{
+		"host": "10.0.0.1",
+		"port": 8080,
+		"kubernetes": mapstr.M{
+			"namespace": "Default",
+			"pod": mapstr.M{
+				"name": "nginx-1",
+			},
+			"labels": mapstr.M{
+				"app":   "",
+				"group": "Web",
+			},
+			"hosts": []string{"a", "b"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		err      bool
+	}{
+		{name: "no references", value: "plain value", expected: "plain value"},
+		{name: "reference", value: "${data.kubernetes.pod.name}", expected: "nginx-1"},
+		{name: "references in text", value: "http://${data.host}:${data.port}/metrics", expected: "http://10.0.0.1:8080/metrics"},
+		{name: "agent fallback to field", value: "${data.kubernetes.labels.tier|data.kubernetes.namespace}", expected: "Default"},
+		{name: "agent fallback to constant", value: "${data.kubernetes.labels.tier|data.missing|'backend'}", expected: "backend"},
+		{name: "agent fallback to double quoted constant", value: `${data.kubernetes.labels.tier|"back\tend"}`, expected: "back\tend"},
+		{name: "agent fallback not used for empty values", value: "${data.kubernetes.labels.app|'unknown'}", expected: ""},
+		{name: "agent fallback with spaces", value: "${ data.missing | 'a b' }", expected: "a b"},
+		{name: "beats default", value: "${data.kubernetes.labels.tier:backend}", expected: "backend"},
+		{name: "beats default not used", value: "${data.host:localhost}", expected: "10.0.0.1"},
+		{name: "beats default with colons", value: "${data.missing:http://localhost:8080}", expected: "http://localhost:8080"},
+		{name: "beats default after fallbacks", value: "${data.missing|data.other:x}", expected: "x"},
+		{name: "quoted colon in fallback", value: "${data.missing|'a:b'}", expected: "a:b"},
+		{name: "lower", value: "${lower(data.kubernetes.namespace)}", expected: "default"},
+		{name: "default for missing", value: "${default(data.kubernetes.labels.tier, 'backend')}", expected: "backend"},
+		{name: "default for empty", value: "${default(data.kubernetes.labels.app, 'unknown')}", expected: "unknown"},
+		{name: "default not used", value: "${lower(default(data.kubernetes.labels.group, 'x'))}", expected: "web"},
+		{name: "default with field", value: "${default(data.kubernetes.labels.app, data.kubernetes.pod.name)}", expected: "nginx-1"},
+		{name: "default then lower", value: "${lower(default(data.kubernetes.labels.tier, 'Back End'))}", expected: "back end"},
+		{name: "function with fallback", value: "${lower(data.kubernetes.labels.tier)|'unknown'}", expected: "unknown"},
+		{name: "join", value: "${join(data.kubernetes.hosts, ';')}", expected: "a;b"},
+		{name: "join with quoted separator", value: `${join(data.kubernetes.hosts, ", ")}`, expected: "a, b"},
+		{name: "join with separator with parentheses", value: "${join(data.kubernetes.hosts, ')|(')}", expected: "a)|(b"},
+		{name: "lists joined with commas", value: "${data.kubernetes.hosts}", expected: "a,b"},
+		{name: "quoted braces", value: "${data.missing|'{}'}", expected: "{}"},
+		{name: "escaped reference", value: "$${data.host} is ${data.host}", expected: "${data.host} is 10.0.0.1"},
+		{name: "missing value", value: "${data.kubernetes.labels.tier}", err: true},
+		{name: "missing alternatives", value: "${data.kubernetes.labels.tier|data.missing}", err: true},
+		{name: "not data", value: "${kubernetes.pod.name}", err: true},
+		{name: "unknown function", value: "${upper(data.host)}", err: true},
+		{name: "wrong arguments", value: "${default(data.host)}", err: true},
+		{name: "unterminated function call", value: "${lower(data.host}", err: true},
+		{name: "unterminated reference", value: "${data.host", err: true},
+		{name: "unterminated string", value: "${data.missing|'x}", err: true},
+		{name: "empty alternative", value: "${data.missing|}", err: true},
+		{name: "empty reference", value: "${}", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolved, err := ResolveHintTemplate(test.value, data)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, resolved)
+		})
+	}
+}
+
+func TestResolveHintsTemplates(t *testing.T) {
+	data := mapstr.M{
+		"kubernetes": mapstr.M{
+			"pod": mapstr.M{"name": "nginx-1"},
+		},
+	}
+	hints := mapstr.M{
+		"logs": mapstr.M{
+			"multiline": mapstr.M{
+				"pattern": "^${data.kubernetes.pod.name}",
+			},
+			"tags":  "${data.missing}",
+			"other": 1,
+		},
+	}
+
+	resolved, err := ResolveHintsTemplates(hints, data)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "logs.tags")
+	assert.Equal(t, mapstr.M{
+		"logs": mapstr.M{
+			"multiline": mapstr.M{
+				"pattern": "^nginx-1",
+			},
+			"tags":  "${data.missing}",
+			"other": 1,
+		},
+	}, resolved)
+	assert.Equal(t, "^${data.kubernetes.pod.name}", hints["logs"].(mapstr.M)["multiline"].(mapstr.M)["pattern"], "hints should not be modified")
+}
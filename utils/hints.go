@@ -105,32 +105,20 @@ func GetProcessors(hints mapstr.M, key string, logger *logp.Logger) []mapstr.M {
 	return processors
 }
 
-// GetConfigs takes in a key and returns a list of configs as a slice of MapStr
+// GetConfigs takes in a key and returns a list of configs as a slice of MapStr.
+// The configs of the enumerated blocks are returned first, in the order described in enumeratedBlocks,
+// followed by a config for each of the rest of hints, sorted by name.
 func GetConfigs(hints mapstr.M, key, name string) []mapstr.M {
 	raw := GetHintMapStr(hints, key, name)
 	if raw == nil {
 		return nil
 	}
 
-	var words, nums []string
-
-	for key := range raw {
-		if _, err := strconv.Atoi(key); err != nil {
-			words = append(words, key)
-			continue
-		} else {
-			nums = append(nums, key)
-		}
-	}
-
-	sort.Strings(nums)
+	blocks, words := enumeratedBlocks(raw)
 
 	var configs []mapstr.M
-	for _, key := range nums {
-		rawCfg := raw[key]
-		if config, ok := rawCfg.(mapstr.M); ok {
-			configs = append(configs, config)
-		}
+	for _, block := range blocks {
+		configs = append(configs, block.config)
 	}
 
 	for _, word := range words {
@@ -142,6 +130,73 @@ func GetConfigs(hints mapstr.M, key, name string) []mapstr.M {
 	return configs
 }
 
+// HintPriorityKey is the hint setting the priority of an enumerated block, like metrics/1.priority.
+// It is removed from the configs of the blocks.
+const HintPriorityKey = "priority"
+
+// parseHintPriority parses the value of a priority hint, that must be an integer
+func parseHintPriority(value any) (int, error) {
+	str, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid priority %v", value)
+	}
+	return strconv.Atoi(strings.TrimSpace(str))
+}
+
+// enumeratedBlock is a block of hints with a numeric key, like 1 in metrics/1.module
+type enumeratedBlock struct {
+	key      string
+	number   int
+	priority int
+	config   mapstr.M
+}
+
+// enumeratedBlocks returns the enumerated blocks of raw in the order their configs are generated, and the sorted
+// keys of the rest of hints. Blocks are sorted by their priority hint in increasing order, blocks without priority
+// have priority 0, and then by their number in numeric order, so 2 goes before 10.
+// Enumerated keys whose value is not a block of hints are ignored.
+func enumeratedBlocks(raw mapstr.M) ([]enumeratedBlock, []string) {
+	var words []string
+	var blocks []enumeratedBlock
+
+	for key, value := range raw {
+		number, err := strconv.Atoi(key)
+		if err != nil {
+			words = append(words, key)
+			continue
+		}
+		config, ok := value.(mapstr.M)
+		if !ok {
+			continue
+		}
+
+		block := enumeratedBlock{key: key, number: number, config: config}
+		if rawPriority, ok := config[HintPriorityKey]; ok {
+			// Invalid priorities are reported by the validation of hints, blocks with them keep priority 0
+			block.priority, _ = parseHintPriority(rawPriority)
+			block.config = mapstr.M{}
+			for k, v := range config {
+				if k != HintPriorityKey {
+					block.config[k] = v
+				}
+			}
+		}
+		blocks = append(blocks, block)
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].priority != blocks[j].priority {
+			return blocks[i].priority < blocks[j].priority
+		}
+		if blocks[i].number != blocks[j].number {
+			return blocks[i].number < blocks[j].number
+		}
+		return blocks[i].key < blocks[j].key
+	})
+	sort.Strings(words)
+	return blocks, words
+}
+
 func getStringAsList(input string) []string {
 	if input == "" {
 		return []string{}
@@ -242,7 +297,6 @@ func GenerateHints(annotations mapstr.M, container, prefix string, validate bool
 			} //end of annotation check
 
 			for key, rawValue := range entries {
-				enumeratedmodules := []string{}
 				// If there are top level hints like co.elastic.logs/ then just add the values after the /
 				// Only consider namespaced annotations
 				parts := strings.Split(key, "/")
@@ -254,24 +308,13 @@ func GenerateHints(annotations mapstr.M, container, prefix string, validate bool
 						if checkdigit {
 							allSupportedHints = append(allSupportedHints, parts[1])
 
-							specificlist, _ := entries.GetValue(key)
-							if specificentries, ok := specificlist.(mapstr.M); ok {
-								for keyspec := range specificentries {
-									// enumeratedmodules will be populated only in cases we have module enumeration, like:
-									// "co.elastic.metrics/1.module":  "prometheus",
-									// "co.elastic.metrics/2.module":  "istiod",
-									enumeratedmodules = append(enumeratedmodules, keyspec)
-								}
-							}
-						}
-						// We check if multiple metrcisets are defined and we retrieve the hints per metricset. Only applicable in beats
-						// See Metrics_multiple_modules_and_specific_config_per_module test case in hints_test.go
-						for _, metric := range enumeratedmodules {
-							_, incorrecthint = checkSupportedHints(metric, fmt.Sprintf("%s.%s", key, metric), allSupportedHints)
-							if incorrecthint != "" {
-								incorrecthints = append(incorrecthints, incorrecthint)
+							// We check the hints of each enumerated module, like:
+							// "co.elastic.metrics/1.module":  "prometheus",
+							// "co.elastic.metrics/2.module":  "istiod",
+							// See Metrics_multiple_modules_and_specific_config_per_module test case in hints_test.go
+							if block, ok := rawValue.(mapstr.M); ok {
+								incorrecthints = checkEnumeratedHints(block, key, allSupportedHints, incorrecthints)
 							}
-
 						}
 						//We check whether the provided annotation follows the supported format and vocabulary. The check happens for annotations that have prefix co.elastic
 						_, incorrecthint = checkSupportedHints(parts[1], key, allSupportedHints)
@@ -304,26 +347,12 @@ func GenerateHints(annotations mapstr.M, container, prefix string, validate bool
 								if checkdigit {
 									allSupportedHints = append(allSupportedHints, parts[1])
 
-									specificlist, _ := entries.GetValue(key)
-									if specificentries, ok := specificlist.(mapstr.M); ok {
-										for keyspec := range specificentries {
-											// enumeratedmodules will be populated only in cases we have module enumeration, like:
-											// "co.elastic.metrics/1.module":  "prometheus",
-											// "co.elastic.metrics/2.module":  "istiod",
-											enumeratedmodules = append(enumeratedmodules, keyspec)
-										}
+									// We check the hints of each enumerated module of the container, like:
+									// "co.elastic.metrics.nginx/1.module":  "prometheus",
+									if block, ok := rawVal.(mapstr.M); ok {
+										incorrecthints = checkEnumeratedHints(block, key+"."+hintKey, allSupportedHints, incorrecthints)
 									}
 								}
-
-								// We check if multiple metrcisets are defined and we retrieve the hints per metricset. Only applicable in beats
-								// See Metrics_multiple_modules_and_specific_config_per_module test case in hints_test.go
-								for _, metric := range enumeratedmodules {
-									_, incorrecthint = checkSupportedHints(metric, fmt.Sprintf("%s.%s", key, metric), allSupportedHints)
-									if incorrecthint != "" {
-										incorrecthints = append(incorrecthints, incorrecthint)
-									}
-
-								}
 								//We check whether the provided annotation follows the supported format and vocabulary. The check happens for annotations that have prefix co.elastic
 								_, incorrecthint = checkSupportedHints(parts[1], key, allSupportedHints)
 							} //end of annotation check
@@ -349,32 +378,21 @@ func GenerateHints(annotations mapstr.M, container, prefix string, validate bool
 	return hints, incorrecthints
 }
 
-// GetHintsAsList gets a set of hints and tries to convert them into a list of hints
+// GetHintsAsList gets a set of hints and tries to convert them into a list of hints.
+// The hints of the enumerated blocks, like metrics/1.module and metrics/2.module, are returned first, in the order
+// described in enumeratedBlocks, followed by the default block with the hints that are not enumerated, like
+// metrics/module. Blocks are not merged, so hints of the default block don't apply to the enumerated blocks.
 func GetHintsAsList(hints mapstr.M, key string) []mapstr.M {
 	raw := GetHintMapStr(hints, key, "")
 	if raw == nil {
 		return nil
 	}
 
-	var words, nums []string
-
-	for key := range raw {
-		if _, err := strconv.Atoi(key); err != nil {
-			words = append(words, key)
-			continue
-		} else {
-			nums = append(nums, key)
-		}
-	}
-
-	sort.Strings(nums)
+	blocks, words := enumeratedBlocks(raw)
 
 	var configs []mapstr.M
-	for _, key := range nums {
-		rawCfg := raw[key]
-		if config, ok := rawCfg.(mapstr.M); ok {
-			configs = append(configs, config)
-		}
+	for _, block := range blocks {
+		configs = append(configs, block.config)
 	}
 
 	defaultMap := mapstr.M{}
@@ -402,6 +420,24 @@ func checkSupportedHints(actualannotation, key string, allSupportedHints []strin
 	return found, incorrecthint
 }
 
+// checkEnumeratedHints checks the hints of an enumerated block against the supported list of hints and merges the
+// incorrect hints found with the rest. The priority hint is reserved in enumerated blocks, it is valid if it is numeric.
+func checkEnumeratedHints(block mapstr.M, key string, allSupportedHints, incorrecthints []string) []string {
+	for hint, value := range block {
+		hintKey := fmt.Sprintf("%s.%s", key, hint)
+		if hint == HintPriorityKey {
+			if _, err := parseHintPriority(value); err != nil {
+				incorrecthints = append(incorrecthints, hintKey)
+			}
+			continue
+		}
+		if _, incorrecthint := checkSupportedHints(hint, hintKey, allSupportedHints); incorrecthint != "" {
+			incorrecthints = append(incorrecthints, incorrecthint)
+		}
+	}
+	return incorrecthints
+}
+
 // checkSupportedHintsSets gest the data_streams or metricset lists that are defined. Searches inside specific list and returns the unsupported list of hints found
 // This function will merge the incorrect hints found in metricsets of data_streams with rest incorrect hints
 func checkSupportedHintsSets(annotations mapstr.M, prefix, stream, kind string, allSupportedHints, incorrecthints []string) []string {
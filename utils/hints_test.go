@@ -301,6 +301,36 @@ func TestGenerateHints(t *testing.T) {
 				}},
 			expectedIncorrectHints: 2, // Due to co.elastic.metrics/1.periodssssssssss and co.elastic.metrics/2.streamssssssssss typo errors
 		},
+		// Scenarios being tested:
+		// priority is a reserved hint of enumerated modules, it is only reported when it is not numeric
+		// "co.elastic.metrics/2.priority" is not numeric
+		{
+			name: "Metrics_multiple_modules_with_priority",
+			annotations: map[string]string{
+				"co.elastic.metrics/1.module":          "prometheus",
+				"co.elastic.metrics/1.priority":        "10",
+				"co.elastic.metrics/2.module":          "istiod",
+				"co.elastic.metrics/2.priority":        "high",
+				"co.elastic.metrics.foobar/3.module":   "redis",
+				"co.elastic.metrics.foobar/3.priority": "-1",
+			},
+			result: mapstr.M{
+				"metrics": mapstr.M{
+					"1": mapstr.M{
+						"module":   "prometheus",
+						"priority": "10",
+					},
+					"2": mapstr.M{
+						"module":   "istiod",
+						"priority": "high",
+					},
+					"3": mapstr.M{
+						"module":   "redis",
+						"priority": "-1",
+					},
+				}},
+			expectedIncorrectHints: 1, // Due to co.elastic.metrics/2.priority not being numeric
+		},
 	}
 
 	for _, test := range tests {
@@ -505,6 +535,38 @@ func TestGetHintsAsList(t *testing.T) {
 			},
 			message: "Multiple hints with numeric prefix and default should return configs with defaults at the last",
 		},
+		{
+			input: mapstr.M{
+				"metrics": mapstr.M{
+					"2":  mapstr.M{"module": "two"},
+					"10": mapstr.M{"module": "ten"},
+					"1":  mapstr.M{"module": "one"},
+				},
+			},
+			output: []mapstr.M{
+				{"module": "one"},
+				{"module": "two"},
+				{"module": "ten"},
+			},
+			message: "Hints with numeric prefix should be sorted numerically",
+		},
+		{
+			input: mapstr.M{
+				"metrics": mapstr.M{
+					"1":  mapstr.M{"module": "one"},
+					"2":  mapstr.M{"module": "two", "priority": "-1"},
+					"3":  mapstr.M{"module": "three", "priority": "5"},
+					"10": mapstr.M{"module": "ten"},
+				},
+			},
+			output: []mapstr.M{
+				{"module": "two"},
+				{"module": "one"},
+				{"module": "ten"},
+				{"module": "three"},
+			},
+			message: "Hints with priority should be sorted by priority and then numerically",
+		},
 	}
 
 	for _, test := range tests {
@@ -513,3 +575,28 @@ func TestGetHintsAsList(t *testing.T) {
 		})
 	}
 }
+
+func TestGetConfigs(t *testing.T) {
+	hints := mapstr.M{
+		"logs": mapstr.M{
+			"processors": mapstr.M{
+				"10":         mapstr.M{"drop_event": "ten"},
+				"9":          mapstr.M{"drop_event": "nine"},
+				"1":          mapstr.M{"add_fields": "one", "priority": "20"},
+				"rename":     "rename",
+				"add_labels": "labels",
+			},
+		},
+	}
+
+	assert.Equal(t, []mapstr.M{
+		{"drop_event": "nine"},
+		{"drop_event": "ten"},
+		{"add_fields": "one"},
+		{"add_labels": "labels"},
+		{"rename": "rename"},
+	}, GetConfigs(hints, "logs", "processors"))
+
+	processors, _ := hints.GetValue("logs.processors.1")
+	assert.Equal(t, mapstr.M{"add_fields": "one", "priority": "20"}, processors, "hints should not be modified")
+}